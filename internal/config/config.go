@@ -1,22 +1,67 @@
 package config
 
 import (
+	"errors"
 	"time"
 )
 
+// maxUDPPayload is the largest payload a single IPv4 UDP datagram can carry
+const maxUDPPayload = 65507
+
 // Config is the root configuration for the entire system
 type Config struct {
 	Node            NodeConfig
+	Cluster         ClusterConfig
 	Gossip          GossipConfig
 	FailureDetector FailureDetectorConfig
+	Membership      MembershipConfig
+	Selection       SelectionConfig
+	Compression     CompressionConfig
 }
 
 // DefaultConfig returns defaults for all components
 func DefaultConfig() Config {
 	config := Config{
-		Node:            NodeConfig{},
-		Gossip:          GossipConfig{},
-		FailureDetector: FailureDetectorConfig{},
+		Node: NodeConfig{
+			BindAddr: "0.0.0.0",
+			BindPort: "7946",
+		},
+		Cluster: ClusterConfig{
+			ID:          "default",
+			MergePolicy: MergeAuto,
+		},
+		Gossip: GossipConfig{
+			MaxBroadcast:       1024,
+			MaxGossipEntries:   16,
+			MaxPacketSize:      1400,
+			RetransmitMult:     4,
+			PushPullInterval:   30 * time.Second,
+			StreamTimeout:      10 * time.Second,
+			MaxStreamConns:     8,
+			MaxStreamFrameSize: 4 << 20,
+			GossipNodes:        3,
+
+			ChecksumSyncInterval: 5 * time.Second,
+		},
+		FailureDetector: FailureDetectorConfig{
+			ProbeInterval: time.Second,
+			ProbeTimeout:  500 * time.Millisecond,
+			IndirectNodes: 3,
+			SuspicionMult: 4,
+
+			SuspicionMaxTimeoutMult: 6,
+		},
+		Membership: MembershipConfig{
+			TombstoneRetention: time.Hour,
+			ReapInterval:       time.Minute,
+			ConflictPolicy:     ConflictKeepExisting,
+			ConflictWindow:     30 * time.Second,
+			SnapshotInterval:   time.Minute,
+		},
+		Selection: SelectionConfig{
+			Strategy:       SelectUniform,
+			RemoteFraction: 0.2,
+		},
 	}
 	config.Validate()
 	return config
@@ -35,12 +80,24 @@ func (c *Config) Validate() error {
 	if err = c.Node.Validate(); err != nil {
 		return err
 	}
+	if err = c.Cluster.Validate(); err != nil {
+		return err
+	}
 	if err = c.Gossip.Validate(); err != nil {
 		return err
 	}
 	if err = c.FailureDetector.Validate(); err != nil {
 		return err
 	}
+	if err = c.Membership.Validate(); err != nil {
+		return err
+	}
+	if err = c.Selection.Validate(); err != nil {
+		return err
+	}
+	if err = c.Compression.Validate(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -49,9 +106,56 @@ type NodeConfig struct {
 	ID       string
 	BindAddr string
 	BindPort string
+
+	// AdvertiseAddr is the host:port peers use to reach this node
+	// Defaults to the transport's local address when empty, with a
+	// wildcard BindAddr replaced by the address of a local interface
+	AdvertiseAddr string
+
+	// DataDir holds state kept across restarts, such as the membership
+	// snapshot. Empty disables persistence
+	DataDir string
 }
 
 func (c *NodeConfig) Validate() error {
+	if c.ID == "" {
+		return errors.New("node id is required")
+	}
+	return nil
+}
+
+// ClusterConfig identifies the cluster this node belongs to and decides
+// which other clusters it merges with when their nodes meet
+type ClusterConfig struct {
+	ID   string // Cluster this node was started in
+	Team string // Team this node belongs to, reported to the clusters it meets
+
+	MergePolicy     MergePolicy
+	AllowedClusters []string // Clusters merged with under MergeAllowList
+}
+
+// MergePolicy decides whether messages from another cluster are accepted
+type MergePolicy string
+
+const (
+	MergeAuto      MergePolicy = "auto"       // merge with any cluster
+	MergeAllowList MergePolicy = "allow-list" // merge only with AllowedClusters
+	MergeRefuse    MergePolicy = "refuse"     // ignore every other cluster
+)
+
+func (c *ClusterConfig) Validate() error {
+	if c.ID == "" {
+		return errors.New("cluster id is required")
+	}
+	switch c.MergePolicy {
+	case MergeAuto, MergeRefuse:
+	case MergeAllowList:
+		if len(c.AllowedClusters) == 0 {
+			return errors.New("allow-list merge policy requires allowed clusters")
+		}
+	default:
+		return errors.New("unknown merge policy")
+	}
 	return nil
 }
 
@@ -64,9 +168,28 @@ type FailureDetectorConfig struct {
 	ProbeTimeout  time.Duration
 	IndirectNodes int
 	SuspicionMult int
+
+	// SuspicionMaxTimeoutMult scales the suspicion timeout used when no other
+	// node has confirmed the suspicion
+	SuspicionMaxTimeoutMult int
 }
 
 func (c *FailureDetectorConfig) Validate() error {
+	if c.ProbeInterval <= 0 {
+		return errors.New("probe interval must be positive")
+	}
+	if c.ProbeTimeout <= 0 || c.ProbeTimeout >= c.ProbeInterval {
+		return errors.New("probe timeout must be positive and shorter than the probe interval")
+	}
+	if c.IndirectNodes < 0 {
+		return errors.New("indirect nodes cannot be negative")
+	}
+	if c.SuspicionMult <= 0 {
+		return errors.New("suspicion mult must be positive")
+	}
+	if c.SuspicionMaxTimeoutMult < 1 {
+		return errors.New("suspicion max timeout mult must be at least 1")
+	}
 	return nil
 }
 
@@ -75,14 +198,193 @@ func (c *FailureDetectorConfig) OverwriteStringProperty(key string, value any) {
 
 // GossipConfig tunes the SWIM protocol
 type GossipConfig struct {
-	MaxBroadcast     int
-	MaxGossipEntries int
-	MaxPacketSize    int
+	MaxBroadcast     int // Most entries held in the broadcast queue
+	MaxGossipEntries int // Most entries piggybacked on a single message
+	MaxPacketSize    int // Largest datagram sent or accepted, in bytes
+	RetransmitMult   int // Each entry is sent RetransmitMult * log(N) times
+
+	// GossipInterval is how often queued entries are sent to GossipNodes
+	// peers picked by the selection strategy. Probes then only carry
+	// entries to peers we know nothing about yet or that do not advertise
+	// decoding Gossip messages. Zero, the default, piggybacks entries on
+	// every probe message instead
+	GossipInterval time.Duration
+	GossipNodes    int // Peers gossiped to every GossipInterval
+
+	// PushPullInterval is how often full state is exchanged with a random
+	// peer over the stream transport. Zero disables periodic push-pull
+	PushPullInterval time.Duration
+
+	StreamTimeout      time.Duration // Deadline for a single stream exchange
+	MaxStreamConns     int           // Bound on pooled and in-flight stream connections
+	MaxStreamFrameSize int           // Largest stream frame sent or accepted, in bytes
+
+	// ChecksumSyncInterval is the least time between push-pulls triggered
+	// by a peer reporting a different membership checksum, or between
+	// datagrams pushing our state to such peers without a stream transport.
+	// Zero disables them
+	ChecksumSyncInterval time.Duration
+
+	// BatchDelay is how long an outgoing message may wait for others to
+	// the same peer, so they can share a datagram. Zero only batches
+	// messages that are already queued together
+	BatchDelay time.Duration
 }
 
 func (c *GossipConfig) Validate() error {
+	if c.MaxBroadcast <= 0 {
+		return errors.New("max broadcast must be positive")
+	}
+	if c.MaxGossipEntries <= 0 {
+		return errors.New("max gossip entries must be positive")
+	}
+	if c.RetransmitMult <= 0 {
+		return errors.New("retransmit mult must be positive")
+	}
+	if c.GossipInterval < 0 {
+		return errors.New("gossip interval cannot be negative")
+	}
+	if c.GossipInterval > 0 && c.GossipNodes <= 0 {
+		return errors.New("gossip nodes must be positive")
+	}
+	if c.PushPullInterval < 0 {
+		return errors.New("push pull interval cannot be negative")
+	}
+	if c.MaxPacketSize <= 0 {
+		return errors.New("max packet size must be positive")
+	}
+	if c.MaxPacketSize > maxUDPPayload {
+		return errors.New("max packet size exceeds the UDP payload limit")
+	}
+	if c.StreamTimeout <= 0 {
+		return errors.New("stream timeout must be positive")
+	}
+	if c.MaxStreamConns <= 0 {
+		return errors.New("max stream conns must be positive")
+	}
+	if c.MaxStreamFrameSize <= 0 {
+		return errors.New("max stream frame size must be positive")
+	}
+	if c.ChecksumSyncInterval < 0 {
+		return errors.New("checksum sync interval cannot be negative")
+	}
+	if c.BatchDelay < 0 {
+		return errors.New("batch delay cannot be negative")
+	}
 	return nil
 }
 
 func (c *GossipConfig) OverwriteStringProperty(key string, value any) {
 }
+
+// MembershipConfig controls how long departed members are remembered
+type MembershipConfig struct {
+	// TombstoneRetention is how long dead and left members are kept, so stale
+	// gossip cannot resurrect them. It should comfortably outlast the time
+	// an entry takes to spread through the cluster
+	TombstoneRetention time.Duration
+
+	ReapInterval time.Duration // How often expired tombstones are purged
+
+	// ConflictPolicy decides which address wins when two nodes claim the
+	// same ID, either at the same incarnation or by moving the ID back and
+	// forth between their addresses
+	ConflictPolicy ConflictPolicy
+
+	// ConflictWindow is how recent the moves back and forth must be to
+	// count as two nodes claiming one ID. The losing address is ignored
+	// for as long afterwards
+	ConflictWindow time.Duration
+
+	// SnapshotInterval is how often the membership is saved to the data
+	// directory. Zero saves only on shutdown
+	SnapshotInterval time.Duration
+}
+
+// ConflictPolicy resolves conflicting claims to a single node ID
+type ConflictPolicy string
+
+const (
+	ConflictKeepExisting ConflictPolicy = "keep-existing" // ignore the newcomer
+	ConflictPreferNew    ConflictPolicy = "prefer-new"    // switch to the newcomer's address
+)
+
+func (c *MembershipConfig) Validate() error {
+	if c.TombstoneRetention <= 0 {
+		return errors.New("tombstone retention must be positive")
+	}
+	if c.ReapInterval <= 0 {
+		return errors.New("reap interval must be positive")
+	}
+	if c.SnapshotInterval < 0 {
+		return errors.New("snapshot interval cannot be negative")
+	}
+	if c.ConflictWindow <= 0 {
+		return errors.New("conflict window must be positive")
+	}
+	switch c.ConflictPolicy {
+	case ConflictKeepExisting, ConflictPreferNew:
+	default:
+		return errors.New("unknown conflict policy")
+	}
+	return nil
+}
+
+// SelectionConfig chooses how peers are picked for probing and gossip
+type SelectionConfig struct {
+	Strategy SelectionStrategy
+
+	// LocalityLabel is the label whose value names a node's locality, used
+	// by the locality strategy
+	LocalityLabel string
+
+	// RemoteFraction is the share of selections the locality strategy
+	// reserves for nodes in other localities, between 0 and 1
+	RemoteFraction float64
+}
+
+// SelectionStrategy names a built-in peer selection strategy
+type SelectionStrategy string
+
+const (
+	SelectUniform  SelectionStrategy = "uniform"  // every live node equally
+	SelectPriority SelectionStrategy = "priority" // weighted by node priority
+	SelectLocality SelectionStrategy = "locality" // same locality first, with a remote share
+)
+
+func (c *SelectionConfig) Validate() error {
+	switch c.Strategy {
+	case SelectUniform, SelectPriority:
+	case SelectLocality:
+		if c.LocalityLabel == "" {
+			return errors.New("locality selection requires a locality label")
+		}
+	default:
+		return errors.New("unknown selection strategy")
+	}
+	if c.RemoteFraction < 0 || c.RemoteFraction > 1 {
+		return errors.New("remote fraction must be between 0 and 1")
+	}
+	return nil
+}
+
+// CompressionConfig chooses which peers get compressed messages, when the
+// codec compresses at all. Only peers advertising that they decode
+// compressed payloads are considered. With neither Peers nor Selector set,
+// all of them get compressed messages
+type CompressionConfig struct {
+	Peers []string // node IDs to compress for
+
+	// Selector is a label selector, such as "link in (radio,satellite)".
+	// Peers whose labels match it are compressed for
+	Selector string
+}
+
+func (c *CompressionConfig) Validate() error {
+	for _, peer := range c.Peers {
+		if peer == "" {
+			return errors.New("compression peers cannot be empty")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package gossip
+
+import "github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+
+// addressIndex maps every address to the members at it, so finding the
+// sender of a datagram does not scan the whole membership. More than one
+// member can share an address when a node restarts under a new ID
+// It is not safe for concurrent use; the membership guards it with its lock
+type addressIndex struct {
+	byAddress map[string]map[types.NodeID]struct{}
+	addresses map[types.NodeID]string // what each member is indexed under
+}
+
+func newAddressIndex() *addressIndex {
+	return &addressIndex{
+		byAddress: make(map[string]map[types.NodeID]struct{}),
+		addresses: make(map[types.NodeID]string),
+	}
+}
+
+// set indexes id under addr, replacing whatever it was indexed under
+func (x *addressIndex) set(id types.NodeID, addr string) {
+	if current, exists := x.addresses[id]; exists && current == addr {
+		return
+	}
+	x.delete(id)
+
+	ids, exists := x.byAddress[addr]
+	if !exists {
+		ids = make(map[types.NodeID]struct{})
+		x.byAddress[addr] = ids
+	}
+	ids[id] = struct{}{}
+	x.addresses[id] = addr
+}
+
+// delete drops id from the index
+func (x *addressIndex) delete(id types.NodeID) {
+	addr, exists := x.addresses[id]
+	if !exists {
+		return
+	}
+
+	ids := x.byAddress[addr]
+	delete(ids, id)
+	if len(ids) == 0 {
+		delete(x.byAddress, addr)
+	}
+	delete(x.addresses, id)
+}
+
+// at returns the members at addr
+func (x *addressIndex) at(addr string) map[types.NodeID]struct{} {
+	return x.byAddress[addr]
+}
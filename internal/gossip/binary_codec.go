@@ -0,0 +1,500 @@
+package gossip
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+var ErrMalformedMessage = errors.New("malformed binary message")
+
+const (
+	binaryMagic byte = 0xA5
+
+	// binaryVersion is the version of the frame layout below. Bump it with
+	// every change to the layout, so builds that disagree on it reject each
+	// other's frames instead of misparsing them. Version 2 added Suspector
+	// and ClusterID to entries and Compound to metadata, version 3 added
+	// MetadataIncarnation to entries, version 4 added Gossip to metadata,
+	// version 5 dropped Join from Sync
+	binaryVersion byte = 5
+)
+
+// binaryCodec encodes messages in a fixed binary layout, without reflection
+// and without gob's type descriptors. Every message is framed as
+//
+//	magic (0xA5) | layout version | message type | flags | string table | body
+//
+// The string table is a uvarint count followed by length-prefixed strings.
+// The body refers to strings by their uvarint position in the table plus
+// one, with zero meaning the empty string, so a NodeID repeated across
+// gossip entries is only sent once. Integers are varints throughout, except
+// the header checksum which is sent as 8 fixed bytes when present. With the
+// frameCompressed flag, everything after the flags is flate compressed
+type binaryCodec struct {
+	opts codecOptions
+}
+
+// NewBinaryCodec returns a Codec producing far smaller messages than the gob
+// codec. Both ends of a connection must use the same codec
+func NewBinaryCodec(opts ...CodecOption) Codec {
+	return binaryCodec{opts: newCodecOptions(opts)}
+}
+
+// frame flags
+const (
+	frameCompressed byte = 1 << iota
+)
+
+// header field flags
+const (
+	hasChecksum byte = 1 << iota
+)
+
+func (c binaryCodec) Encode(msg any) ([]byte, error) {
+	return c.encode(msg, true)
+}
+
+func (c binaryCodec) encode(msg any, compressible bool) ([]byte, error) {
+	msgType := getMessageType(msg)
+	if msgType == 0 {
+		return nil, fmt.Errorf("%w: %T", ErrUnknownMessageType, msg)
+	}
+
+	w := newBinaryWriter()
+	switch m := msg.(type) {
+	case *Ping:
+		w.ping(m)
+	case *Ack:
+		w.header(&m.MessageHeader)
+		w.entries(m.Gossip)
+	case *PingReq:
+		w.ping(&m.Ping)
+		w.string(m.TargetAdder)
+	case *Nack:
+		w.header(&m.MessageHeader)
+	case *Leave:
+		w.header(&m.MessageHeader)
+		w.uvarint(m.Incarnation)
+	case *Sync:
+		w.header(&m.MessageHeader)
+		w.entries(m.Nodes)
+	case *SyncResponse:
+		w.header(&m.MessageHeader)
+		w.entries(m.Nodes)
+	case *Compound:
+		w.header(&m.MessageHeader)
+		w.uvarint(uint64(len(m.Messages)))
+		for _, part := range m.Messages {
+			w.bytes(part)
+		}
+	case *Gossip:
+		w.header(&m.MessageHeader)
+		w.entries(m.Gossip)
+	}
+
+	data := w.frame(msgType)
+	if compressible && c.opts.shouldCompress(len(data)-4) {
+		if compressed, ok := compress(data[4:]); ok {
+			data = append(data[:4:4], compressed...)
+			data[3] |= frameCompressed
+		}
+	}
+	return data, nil
+}
+
+func (binaryCodec) Decode(data []byte) (any, error) {
+	if len(data) == 0 {
+		return nil, ErrEmptyPayload
+	}
+	if len(data) < 4 || data[0] != binaryMagic {
+		return nil, fmt.Errorf("%w: bad frame", ErrMalformedMessage)
+	}
+	if data[1] != binaryVersion {
+		return nil, fmt.Errorf("%w: layout version %d", ErrMalformedMessage, data[1])
+	}
+	if data[3]&^frameCompressed != 0 {
+		return nil, fmt.Errorf("%w: unknown flags %#x", ErrMalformedMessage, data[3])
+	}
+
+	msgType := MessageType(data[2])
+	msg, err := newMessageByType(msgType)
+	if err != nil {
+		return nil, err
+	}
+
+	body := data[4:]
+	if data[3]&frameCompressed != 0 {
+		if body, err = decompress(body); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformedMessage, err)
+		}
+	}
+
+	r := &binaryReader{data: body}
+	r.stringTable()
+
+	switch m := msg.(type) {
+	case *Ping:
+		r.ping(m, msgType)
+	case *Ack:
+		r.header(&m.MessageHeader, msgType)
+		m.Gossip = r.entries()
+	case *PingReq:
+		r.ping(&m.Ping, msgType)
+		m.TargetAdder = r.string()
+	case *Nack:
+		r.header(&m.MessageHeader, msgType)
+	case *Leave:
+		r.header(&m.MessageHeader, msgType)
+		m.Incarnation = r.uvarint()
+	case *Sync:
+		r.header(&m.MessageHeader, msgType)
+		m.Nodes = r.entries()
+	case *SyncResponse:
+		r.header(&m.MessageHeader, msgType)
+		m.Nodes = r.entries()
+	case *Compound:
+		r.header(&m.MessageHeader, msgType)
+		if n := r.count(1); n > 0 {
+			m.Messages = make([][]byte, n)
+			for i := range m.Messages {
+				m.Messages[i] = r.bytes()
+			}
+		}
+	case *Gossip:
+		r.header(&m.MessageHeader, msgType)
+		m.Gossip = r.entries()
+	}
+
+	if r.err == nil && len(r.data) != 0 {
+		r.fail("%d trailing bytes", len(r.data))
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return msg, nil
+}
+
+// binaryWriter writes a message body while collecting its string table
+type binaryWriter struct {
+	body    []byte
+	strings []string
+	refs    map[string]uint64
+}
+
+func newBinaryWriter() *binaryWriter {
+	return &binaryWriter{
+		body: make([]byte, 0, 128),
+		refs: make(map[string]uint64),
+	}
+}
+
+// frame returns the complete encoded message
+func (w *binaryWriter) frame(msgType MessageType) []byte {
+	size := 4 + binary.MaxVarintLen64 + len(w.body)
+	for _, s := range w.strings {
+		size += binary.MaxVarintLen64 + len(s)
+	}
+
+	out := make([]byte, 0, size)
+	out = append(out, binaryMagic, binaryVersion, byte(msgType), 0)
+	out = binary.AppendUvarint(out, uint64(len(w.strings)))
+	for _, s := range w.strings {
+		out = binary.AppendUvarint(out, uint64(len(s)))
+		out = append(out, s...)
+	}
+	return append(out, w.body...)
+}
+
+func (w *binaryWriter) uvarint(v uint64) {
+	w.body = binary.AppendUvarint(w.body, v)
+}
+
+func (w *binaryWriter) varint(v int64) {
+	w.body = binary.AppendVarint(w.body, v)
+}
+
+func (w *binaryWriter) bool(v bool) {
+	if v {
+		w.body = append(w.body, 1)
+	} else {
+		w.body = append(w.body, 0)
+	}
+}
+
+func (w *binaryWriter) bytes(b []byte) {
+	w.uvarint(uint64(len(b)))
+	w.body = append(w.body, b...)
+}
+
+func (w *binaryWriter) string(s string) {
+	if s == "" {
+		w.uvarint(0)
+		return
+	}
+	ref, ok := w.refs[s]
+	if !ok {
+		w.strings = append(w.strings, s)
+		ref = uint64(len(w.strings))
+		w.refs[s] = ref
+	}
+	w.uvarint(ref)
+}
+
+// header writes h, apart from Type which the frame already carries
+func (w *binaryWriter) header(h *MessageHeader) {
+	var flags byte
+	if h.Checksum != 0 {
+		flags |= hasChecksum
+	}
+
+	w.body = append(w.body, h.Version, flags)
+	w.uvarint(uint64(h.SeqNo))
+	w.string(h.SourceID)
+	if flags&hasChecksum != 0 {
+		w.body = binary.LittleEndian.AppendUint64(w.body, h.Checksum)
+	}
+	w.string(h.ClusterID)
+	w.string(h.Team)
+}
+
+func (w *binaryWriter) ping(p *Ping) {
+	w.header(&p.MessageHeader)
+	w.string(p.Target)
+	w.entries(p.Gossip)
+}
+
+func (w *binaryWriter) entries(entries []GossipEntry) {
+	w.uvarint(uint64(len(entries)))
+	for i := range entries {
+		e := &entries[i]
+		w.string(string(e.NodeID))
+		w.string(e.Address)
+		w.varint(int64(e.State))
+		w.uvarint(e.Incarnation)
+		w.varint(e.Timestamp)
+		w.string(string(e.Suspector))
+		w.string(e.ClusterID)
+		w.bool(e.Metadata != nil)
+		if e.Metadata != nil {
+			w.uvarint(e.MetadataIncarnation)
+			w.metadata(e.Metadata)
+		}
+	}
+}
+
+func (w *binaryWriter) metadata(m *types.NodeMetadata) {
+	w.varint(m.Resources.CPUMillis)
+	w.varint(m.Resources.MemoryBytes)
+	w.varint(m.Resources.DiskBytes)
+	w.varint(int64(m.Priority))
+	w.body = append(w.body, m.Protocol.Min, m.Protocol.Max)
+	w.bool(m.Compression)
+	w.bool(m.Compound)
+	w.bool(m.Gossip)
+
+	// Sorted so that equal metadata always encodes the same way
+	keys := make([]string, 0, len(m.Labels))
+	for key := range m.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	w.uvarint(uint64(len(keys)))
+	for _, key := range keys {
+		w.string(key)
+		w.string(m.Labels[key])
+	}
+}
+
+// binaryReader reads a message body. The first error sticks: later reads
+// return zero values and Decode reports it once at the end
+type binaryReader struct {
+	data    []byte
+	strings []string
+	err     error
+}
+
+func (r *binaryReader) fail(format string, args ...any) {
+	if r.err == nil {
+		r.err = fmt.Errorf("%w: "+format, append([]any{ErrMalformedMessage}, args...)...)
+	}
+	r.data = nil
+}
+
+func (r *binaryReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.fail("bad varint")
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *binaryReader) varint() int64 {
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		r.fail("bad varint")
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *binaryReader) byte() byte {
+	if len(r.data) == 0 {
+		r.fail("unexpected end of data")
+		return 0
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b
+}
+
+func (r *binaryReader) bool() bool {
+	switch b := r.byte(); b {
+	case 0:
+		return false
+	case 1:
+		return true
+	default:
+		r.fail("bad bool %d", b)
+		return false
+	}
+}
+
+// count reads a length that must be backed by at least minSize bytes per item,
+// so corrupt input cannot make us allocate more than the message itself
+func (r *binaryReader) count(minSize int) int {
+	n := r.uvarint()
+	if n > uint64(len(r.data)/minSize) {
+		r.fail("count %d exceeds data", n)
+		return 0
+	}
+	return int(n)
+}
+
+func (r *binaryReader) stringTable() {
+	n := r.count(1)
+	if n == 0 {
+		return
+	}
+	r.strings = make([]string, n)
+	for i := range r.strings {
+		size := r.uvarint()
+		if size > uint64(len(r.data)) {
+			r.fail("string length %d exceeds data", size)
+			return
+		}
+		r.strings[i] = string(r.data[:size])
+		r.data = r.data[size:]
+	}
+}
+
+func (r *binaryReader) bytes() []byte {
+	size := r.uvarint()
+	if size > uint64(len(r.data)) {
+		r.fail("length %d exceeds data", size)
+		return nil
+	}
+	b := make([]byte, size)
+	copy(b, r.data)
+	r.data = r.data[size:]
+	return b
+}
+
+func (r *binaryReader) string() string {
+	ref := r.uvarint()
+	if ref == 0 {
+		return ""
+	}
+	if ref > uint64(len(r.strings)) {
+		r.fail("string ref %d out of range", ref)
+		return ""
+	}
+	return r.strings[ref-1]
+}
+
+func (r *binaryReader) header(h *MessageHeader, msgType MessageType) {
+	h.Type = msgType
+	h.Version = r.byte()
+	flags := r.byte()
+	if flags&^hasChecksum != 0 {
+		r.fail("unknown header flags %#x", flags)
+		return
+	}
+
+	h.SeqNo = uint32(r.uvarint())
+	h.SourceID = r.string()
+	if flags&hasChecksum != 0 {
+		if len(r.data) < 8 {
+			r.fail("unexpected end of data")
+			return
+		}
+		h.Checksum = binary.LittleEndian.Uint64(r.data)
+		r.data = r.data[8:]
+	}
+	h.ClusterID = r.string()
+	h.Team = r.string()
+}
+
+func (r *binaryReader) ping(p *Ping, msgType MessageType) {
+	r.header(&p.MessageHeader, msgType)
+	p.Target = r.string()
+	p.Gossip = r.entries()
+}
+
+func (r *binaryReader) entries() []GossipEntry {
+	// An entry takes at least 8 bytes, one per field
+	n := r.count(8)
+	if n == 0 {
+		return nil
+	}
+
+	entries := make([]GossipEntry, n)
+	for i := range entries {
+		e := &entries[i]
+		e.NodeID = types.NodeID(r.string())
+		e.Address = r.string()
+		e.State = types.NodeState(r.varint())
+		e.Incarnation = r.uvarint()
+		e.Timestamp = r.varint()
+		e.Suspector = types.NodeID(r.string())
+		e.ClusterID = r.string()
+		if r.bool() {
+			e.MetadataIncarnation = r.uvarint()
+			e.Metadata = r.metadata()
+		}
+		if r.err != nil {
+			return nil
+		}
+	}
+	return entries
+}
+
+func (r *binaryReader) metadata() *types.NodeMetadata {
+	m := &types.NodeMetadata{}
+	m.Resources.CPUMillis = r.varint()
+	m.Resources.MemoryBytes = r.varint()
+	m.Resources.DiskBytes = r.varint()
+	m.Priority = int(r.varint())
+	m.Protocol.Min = r.byte()
+	m.Protocol.Max = r.byte()
+	m.Compression = r.bool()
+	m.Compound = r.bool()
+	m.Gossip = r.bool()
+
+	// A label takes at least 2 bytes, one per string ref
+	if n := r.count(2); n > 0 {
+		m.Labels = make(map[string]string, n)
+		for i := 0; i < n; i++ {
+			key := r.string()
+			m.Labels[key] = r.string()
+		}
+	}
+	return m
+}
@@ -0,0 +1,261 @@
+package gossip
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+func TestBinaryCodec_RoundtripAllTypes(t *testing.T) {
+	testRoundtripAll(t, NewBinaryCodec())
+}
+
+func TestBinaryCodec_RoundtripZeroValues(t *testing.T) {
+	// Empty strings, no gossip, bare entries and metadata without labels come
+	// back as the same zero values gob gives
+	codec := NewBinaryCodec()
+
+	for _, original := range []any{
+		&Ping{MessageHeader: MessageHeader{Type: MessageTypePing}},
+		&Ack{MessageHeader: MessageHeader{Type: MessageTypeAck}, Gossip: []GossipEntry{
+			{NodeID: "node2", Metadata: &types.NodeMetadata{}},
+		}},
+		&Ack{MessageHeader: MessageHeader{Type: MessageTypeAck}, Gossip: []GossipEntry{
+			{NodeID: "a"},
+		}},
+		&Sync{MessageHeader: MessageHeader{Type: MessageTypeSync, SeqNo: 1<<32 - 1}},
+	} {
+		data, err := codec.Encode(original)
+		if err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+		decoded, err := codec.Decode(data)
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if !reflect.DeepEqual(decoded, original) {
+			t.Errorf("decoded = %+v, want %+v", decoded, original)
+		}
+	}
+}
+
+func TestBinaryCodec_StringsSentOnce(t *testing.T) {
+	// A NodeID repeated across entries only adds a reference, not the string
+	codec := NewBinaryCodec()
+
+	entry := GossipEntry{NodeID: "a-rather-long-node-identifier", Address: "10.0.0.1:7946", State: types.StateAlive}
+	one, err := codec.Encode(&Ack{MessageHeader: MessageHeader{Type: MessageTypeAck}, Gossip: []GossipEntry{entry}})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	two, err := codec.Encode(&Ack{MessageHeader: MessageHeader{Type: MessageTypeAck}, Gossip: []GossipEntry{entry, entry}})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	if grown := len(two) - len(one); grown > 10 {
+		t.Errorf("second identical entry added %d bytes, want at most 10", grown)
+	}
+}
+
+func TestBinaryCodec_SmallerThanGob(t *testing.T) {
+	binaryCodec, gobCodec := NewBinaryCodec(), NewCodec()
+
+	for _, tt := range allMessages() {
+		compact, err := binaryCodec.Encode(tt.msg)
+		if err != nil {
+			t.Fatalf("%s: binary Encode failed: %v", tt.name, err)
+		}
+		verbose, err := gobCodec.Encode(tt.msg)
+		if err != nil {
+			t.Fatalf("%s: gob Encode failed: %v", tt.name, err)
+		}
+		if len(compact)*3 > len(verbose) {
+			t.Errorf("%s: binary = %d bytes, gob = %d bytes, want at least 3x smaller", tt.name, len(compact), len(verbose))
+		}
+	}
+}
+
+func TestBinaryCodec_EncodeUnknownType(t *testing.T) {
+	codec := NewBinaryCodec()
+
+	if _, err := codec.Encode(&envelope{}); !errors.Is(err, ErrUnknownMessageType) {
+		t.Errorf("Encode err = %v, want ErrUnknownMessageType", err)
+	}
+}
+
+func TestBinaryCodec_DecodeUnknownType(t *testing.T) {
+	// A frame of a type we do not know is skippable, whatever its body
+	codec := NewBinaryCodec()
+
+	_, err := codec.Decode([]byte{binaryMagic, binaryVersion, 200, 0, 0xff, 0xff})
+	var unknown *UnknownMessageTypeError
+	if !errors.As(err, &unknown) || unknown.Type != 200 {
+		t.Errorf("err = %v, want UnknownMessageTypeError for type 200", err)
+	}
+}
+
+func TestBinaryCodec_DecodeEmptyData(t *testing.T) {
+	codec := NewBinaryCodec()
+
+	if _, err := codec.Decode(nil); !errors.Is(err, ErrEmptyPayload) {
+		t.Errorf("err = %v, want ErrEmptyPayload", err)
+	}
+}
+
+func TestBinaryCodec_DecodeBadFrame(t *testing.T) {
+	codec := NewBinaryCodec()
+
+	valid, err := codec.Encode(&Nack{MessageHeader: MessageHeader{Type: MessageTypeNack, SourceID: "node1"}})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	corrupt := func(i int, b byte) []byte {
+		data := append([]byte(nil), valid...)
+		data[i] = b
+		return data
+	}
+
+	tests := map[string][]byte{
+		"bad magic":        corrupt(0, 0x00),
+		"unknown version":  corrupt(1, binaryVersion+1),
+		"unknown flags":    corrupt(3, 0x80),
+		"trailing bytes":   append(append([]byte(nil), valid...), 0),
+		"too short":        valid[:3],
+		"gob data":         mustEncode(t, NewCodec(), &Nack{MessageHeader: MessageHeader{Type: MessageTypeNack}}),
+		"huge string":      {binaryMagic, binaryVersion, byte(MessageTypeNack), 0, 1, 0xff, 0xff, 0x03},
+		"huge entry count": {binaryMagic, binaryVersion, byte(MessageTypeAck), 0, 0, 1, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0x0f},
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := codec.Decode(data); !errors.Is(err, ErrMalformedMessage) {
+				t.Errorf("err = %v, want ErrMalformedMessage", err)
+			}
+		})
+	}
+}
+
+func TestBinaryCodec_DecodeTruncatedData(t *testing.T) {
+	// Every prefix of a valid message must fail cleanly
+	codec := NewBinaryCodec()
+
+	for _, tt := range allMessages() {
+		data := mustEncode(t, codec, tt.msg)
+		for n := 1; n < len(data); n++ {
+			if _, err := codec.Decode(data[:n]); err == nil {
+				t.Errorf("%s: decoding %d of %d bytes succeeded", tt.name, n, len(data))
+			}
+		}
+	}
+}
+
+func TestBinaryCodec_DecodeRandomData(t *testing.T) {
+	// Flipped bytes may still decode, but must never panic
+	codec := NewBinaryCodec()
+	rng := rand.New(rand.NewSource(1))
+
+	for _, tt := range allMessages() {
+		valid := mustEncode(t, codec, tt.msg)
+		for i := 0; i < 1000; i++ {
+			data := append([]byte(nil), valid...)
+			data[4+rng.Intn(len(data)-4)] = byte(rng.Intn(256))
+			_, _ = codec.Decode(data)
+		}
+	}
+}
+
+func mustEncode(t *testing.T, codec Codec, msg any) []byte {
+	t.Helper()
+
+	data, err := codec.Encode(msg)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	return data
+}
+
+// benchmarkPing is a ping carrying a typical amount of piggybacked gossip
+func benchmarkPing() *Ping {
+	ping := &Ping{
+		MessageHeader: MessageHeader{Version: 1, Type: MessageTypePing, SeqNo: 4242, SourceID: "node-01", Checksum: 0x9e3779b97f4a7c15, ClusterID: "default"},
+		Target:        "node-02",
+	}
+	for i := 0; i < 6; i++ {
+		ping.Gossip = append(ping.Gossip, GossipEntry{
+			NodeID:      types.NodeID(fmt.Sprintf("node-%02d", i+3)),
+			Address:     fmt.Sprintf("10.0.0.%d:7946", i+3),
+			State:       types.StateAlive,
+			Incarnation: uint64(i + 1),
+			Timestamp:   1700000000000000000,
+		})
+	}
+	return ping
+}
+
+func BenchmarkCodec_Encode(b *testing.B) {
+	for name, codec := range map[string]Codec{"gob": NewCodec(), "binary": NewBinaryCodec()} {
+		b.Run(name, func(b *testing.B) {
+			msg := benchmarkPing()
+			var size int
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				data, err := codec.Encode(msg)
+				if err != nil {
+					b.Fatal(err)
+				}
+				size = len(data)
+			}
+			b.ReportMetric(float64(size), "bytes/msg")
+		})
+	}
+}
+
+func BenchmarkCodec_Decode(b *testing.B) {
+	for name, codec := range map[string]Codec{"gob": NewCodec(), "binary": NewBinaryCodec()} {
+		b.Run(name, func(b *testing.B) {
+			data, err := codec.Encode(benchmarkPing())
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ReportMetric(float64(len(data)), "bytes/msg")
+			for i := 0; i < b.N; i++ {
+				if _, err := codec.Decode(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestBinaryCodec_LayoutPinned(t *testing.T) {
+	// A change to these bytes is a change to the layout and needs a new
+	// binaryVersion
+	entry := GossipEntry{
+		NodeID: "n", Address: "a", State: types.StateSuspect, Incarnation: 3, Timestamp: 4,
+		Suspector: "s", ClusterID: "c", MetadataIncarnation: 2,
+		Metadata: &types.NodeMetadata{
+			Resources:   types.Resources{CPUMillis: 1, MemoryBytes: 2, DiskBytes: 3},
+			Priority:    5,
+			Protocol:    types.ProtocolRange{Min: ProtocolV1, Max: ProtocolV2},
+			Compression: true,
+			Compound:    true,
+			Gossip:      true,
+			Labels:      map[string]string{"k": "v"},
+		},
+	}
+	data, err := NewBinaryCodec().Encode(&Ack{MessageHeader: MessageHeader{Type: MessageTypeAck, SeqNo: 1}, Gossip: []GossipEntry{entry}})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	const want = "a505030006016e016101730163016b0176000001000000010102020308030401020204060a0102010101010506"
+	if got := fmt.Sprintf("%x", data); got != want {
+		t.Errorf("layout changed:\n got %s\nwant %s", got, want)
+	}
+}
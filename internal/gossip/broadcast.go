@@ -0,0 +1,170 @@
+package gossip
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/michael-martinez-dev/adaptive-hive/internal/config"
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+// BroadcastQueue holds gossip entries waiting to be piggybacked on outgoing
+// messages. Each entry is retransmitted RetransmitMult * log(N) times, after
+// which it is assumed to have reached the whole cluster
+type BroadcastQueue interface {
+	// Queue adds entry, replacing any queued entry for the same NodeID
+	Queue(entry GossipEntry)
+
+	// GetBroadcasts returns up to MaxGossipEntries entries, fewest-sent first,
+	// keeping only those for which fits still reports true, and stops
+	// once a few in a row do not fit. The returned entries are counted as
+	// transmitted
+	GetBroadcasts(fits func([]GossipEntry) bool) []GossipEntry
+
+	// Len returns the number of queued entries
+	Len() int
+
+	// Reset drops every queued entry
+	Reset()
+}
+
+type broadcastQueue struct {
+	cfg      config.GossipConfig
+	numNodes func() int
+
+	mu      sync.Mutex
+	entries map[types.NodeID]*queuedBroadcast
+	seq     uint64
+}
+
+type queuedBroadcast struct {
+	entry     GossipEntry
+	transmits int
+	seq       uint64 // order queued, newer entries win ties
+}
+
+// NewBroadcastQueue creates a BroadcastQueue whose retransmit limit scales
+// with the cluster size reported by numNodes
+func NewBroadcastQueue(cfg config.GossipConfig, numNodes func() int) BroadcastQueue {
+	return &broadcastQueue{
+		cfg:      cfg,
+		numNodes: numNodes,
+		entries:  make(map[types.NodeID]*queuedBroadcast),
+	}
+}
+
+func (q *broadcastQueue) Queue(entry GossipEntry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// A metadata change may not have reached everyone yet, so it outlives
+	// the entry it came with when replaced at the same incarnation
+	if old, ok := q.entries[entry.NodeID]; ok && entry.Metadata == nil && old.entry.Incarnation == entry.Incarnation {
+		entry.Metadata = old.entry.Metadata
+		entry.MetadataIncarnation = old.entry.MetadataIncarnation
+	}
+
+	q.seq++
+	q.entries[entry.NodeID] = &queuedBroadcast{entry: entry, seq: q.seq}
+
+	// When full, drop the entry that has already been sent the most
+	for len(q.entries) > q.cfg.MaxBroadcast {
+		delete(q.entries, q.mostSent().entry.NodeID)
+	}
+}
+
+// maxPackMisses is how many entries in a row may fail to fit before a
+// packet is taken to be full. Each try encodes the whole packet
+const maxPackMisses = 3
+
+func (q *broadcastQueue) GetBroadcasts(fits func([]GossipEntry) bool) []GossipEntry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.entries) == 0 {
+		return nil
+	}
+
+	limit := retransmitLimit(q.cfg.RetransmitMult, q.numNodes())
+	selected := make([]GossipEntry, 0, q.cfg.MaxGossipEntries)
+	misses := 0
+
+	for _, b := range q.ordered() {
+		if len(selected) >= q.cfg.MaxGossipEntries || misses >= maxPackMisses {
+			break
+		}
+
+		candidate := append(selected, b.entry)
+		if fits != nil && !fits(candidate) {
+			misses++
+			continue // a smaller entry further down may still fit
+		}
+		selected = candidate
+		misses = 0
+
+		b.transmits++
+		if b.transmits >= limit {
+			delete(q.entries, b.entry.NodeID)
+		}
+	}
+	return selected
+}
+
+func (q *broadcastQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.entries)
+}
+
+func (q *broadcastQueue) Reset() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.entries = make(map[types.NodeID]*queuedBroadcast)
+}
+
+// ordered returns queued entries fewest-sent first, newest first on ties
+// Must be called with the lock held
+func (q *broadcastQueue) ordered() []*queuedBroadcast {
+	ordered := make([]*queuedBroadcast, 0, len(q.entries))
+	for _, b := range q.entries {
+		ordered = append(ordered, b)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].transmits != ordered[j].transmits {
+			return ordered[i].transmits < ordered[j].transmits
+		}
+		return ordered[i].seq > ordered[j].seq
+	})
+	return ordered
+}
+
+// mostSent returns the entry that ordered would put last
+// Must be called with the lock held and at least one entry queued
+func (q *broadcastQueue) mostSent() *queuedBroadcast {
+	var last *queuedBroadcast
+	for _, b := range q.entries {
+		if last == nil || b.transmits > last.transmits || (b.transmits == last.transmits && b.seq < last.seq) {
+			last = b
+		}
+	}
+	return last
+}
+
+// retransmitLimit is mult * ceil(log10(n + 1)), the λ·log(N) transmissions
+// after which an entry has reached the cluster with high probability
+func retransmitLimit(mult, n int) int {
+	nodeScale := math.Ceil(math.Log10(float64(n) + 1))
+	return mult * int(max(nodeScale, 1))
+}
+
+// fitsPacket returns a fits function for GetBroadcasts that encodes the
+// message built around the candidate entries and checks its size
+func fitsPacket(codec Codec, maxSize int, build func([]GossipEntry) any) func([]GossipEntry) bool {
+	return func(entries []GossipEntry) bool {
+		data, err := codec.Encode(build(entries))
+		return err == nil && len(data) <= maxSize
+	}
+}
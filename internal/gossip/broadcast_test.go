@@ -0,0 +1,242 @@
+package gossip
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/michael-martinez-dev/adaptive-hive/internal/config"
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+// --- Helpers --- //
+
+func testBroadcastConfig() config.GossipConfig {
+	return config.GossipConfig{
+		MaxBroadcast:     32,
+		MaxGossipEntries: 8,
+		MaxPacketSize:    1400,
+		RetransmitMult:   2,
+	}
+}
+
+func fixedNodes(n int) func() int {
+	return func() int { return n }
+}
+
+func TestRetransmitLimit_ScalesWithClusterSize(t *testing.T) {
+	cases := []struct {
+		n    int
+		want int
+	}{
+		{n: 0, want: 3},
+		{n: 1, want: 3},
+		{n: 9, want: 3},
+		{n: 10, want: 6},
+		{n: 99, want: 6},
+		{n: 100, want: 9},
+	}
+
+	for _, tc := range cases {
+		if got := retransmitLimit(3, tc.n); got != tc.want {
+			t.Errorf("retransmitLimit(3, %d) = %d, want %d", tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestBroadcastQueue_RetransmitLimit(t *testing.T) {
+	queue := NewBroadcastQueue(testBroadcastConfig(), fixedNodes(10))
+	queue.Queue(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 1})
+
+	// RetransmitMult 2 * ceil(log10(11)) = 4 transmissions
+	for i := range 4 {
+		if got := queue.GetBroadcasts(nil); len(got) != 1 {
+			t.Fatalf("transmission %d returned %d entries, want 1", i, len(got))
+		}
+	}
+
+	if got := queue.GetBroadcasts(nil); len(got) != 0 {
+		t.Errorf("entry sent %d more times past its limit", len(got))
+	}
+
+	if queue.Len() != 0 {
+		t.Errorf("Len = %d, want 0", queue.Len())
+	}
+}
+
+func TestBroadcastQueue_NewerEntryReplacesOlder(t *testing.T) {
+	queue := NewBroadcastQueue(testBroadcastConfig(), fixedNodes(10))
+	queue.Queue(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 1})
+	_ = queue.GetBroadcasts(nil)
+
+	queue.Queue(GossipEntry{NodeID: "node1", State: types.StateSuspect, Incarnation: 1})
+
+	if queue.Len() != 1 {
+		t.Fatalf("Len = %d, want 1", queue.Len())
+	}
+
+	got := queue.GetBroadcasts(nil)
+	if len(got) != 1 || got[0].State != types.StateSuspect {
+		t.Fatalf("broadcasts = %v, want the suspect entry", got)
+	}
+
+	// The replacement starts with a fresh retransmit count
+	for range 3 {
+		if len(queue.GetBroadcasts(nil)) != 1 {
+			t.Fatal("replacement entry expired early")
+		}
+	}
+}
+
+func TestBroadcastQueue_ReplacementKeepsMetadata(t *testing.T) {
+	// A suspicion replacing a metadata change at the same incarnation still
+	// carries the metadata, a newer incarnation does not
+	queue := NewBroadcastQueue(testBroadcastConfig(), fixedNodes(10))
+	queue.Queue(GossipEntry{NodeID: "node1", Incarnation: 2, Metadata: &types.NodeMetadata{Priority: 1}})
+	queue.Queue(GossipEntry{NodeID: "node1", State: types.StateSuspect, Incarnation: 2})
+
+	got := queue.GetBroadcasts(nil)
+	if len(got) != 1 || got[0].Metadata == nil || got[0].Metadata.Priority != 1 {
+		t.Fatalf("broadcasts = %+v, want the suspect entry with metadata", got)
+	}
+
+	queue.Queue(GossipEntry{NodeID: "node1", Incarnation: 3})
+	if got := queue.GetBroadcasts(nil); got[0].Metadata != nil {
+		t.Errorf("metadata = %+v, want none at a newer incarnation", got[0].Metadata)
+	}
+}
+
+func TestBroadcastQueue_FewestSentFirst(t *testing.T) {
+	cfg := testBroadcastConfig()
+	cfg.MaxGossipEntries = 1
+	queue := NewBroadcastQueue(cfg, fixedNodes(10))
+
+	queue.Queue(GossipEntry{NodeID: "old", State: types.StateAlive, Incarnation: 1})
+	_ = queue.GetBroadcasts(nil)
+
+	queue.Queue(GossipEntry{NodeID: "new", State: types.StateAlive, Incarnation: 1})
+
+	got := queue.GetBroadcasts(nil)
+	if len(got) != 1 || got[0].NodeID != "new" {
+		t.Errorf("broadcasts = %v, want the unsent entry", got)
+	}
+}
+
+func TestBroadcastQueue_MaxGossipEntries(t *testing.T) {
+	cfg := testBroadcastConfig()
+	cfg.MaxGossipEntries = 3
+	queue := NewBroadcastQueue(cfg, fixedNodes(10))
+
+	for i := range 5 {
+		queue.Queue(GossipEntry{NodeID: types.NodeID(fmt.Sprintf("node%d", i)), State: types.StateAlive, Incarnation: 1})
+	}
+
+	if got := queue.GetBroadcasts(nil); len(got) != 3 {
+		t.Errorf("broadcasts = %d, want 3", len(got))
+	}
+}
+
+func TestBroadcastQueue_MaxBroadcastDropsMostSent(t *testing.T) {
+	cfg := testBroadcastConfig()
+	cfg.MaxBroadcast = 2
+	queue := NewBroadcastQueue(cfg, fixedNodes(10))
+
+	queue.Queue(GossipEntry{NodeID: "sent", State: types.StateAlive, Incarnation: 1})
+	_ = queue.GetBroadcasts(nil)
+
+	queue.Queue(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 1})
+	queue.Queue(GossipEntry{NodeID: "node2", State: types.StateAlive, Incarnation: 1})
+
+	if queue.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", queue.Len())
+	}
+
+	for _, entry := range queue.GetBroadcasts(nil) {
+		if entry.NodeID == "sent" {
+			t.Error("the most-sent entry should have been dropped")
+		}
+	}
+}
+
+func TestBroadcastQueue_PackingRespectsMaxPacketSize(t *testing.T) {
+	cfg := testBroadcastConfig()
+	cfg.MaxGossipEntries = 64
+	queue := NewBroadcastQueue(cfg, fixedNodes(10))
+
+	for i := range 32 {
+		queue.Queue(GossipEntry{
+			NodeID:      types.NodeID(fmt.Sprintf("node%02d", i)),
+			Address:     fmt.Sprintf("10.0.0.%d:7946", i),
+			State:       types.StateAlive,
+			Incarnation: 1,
+		})
+	}
+
+	codec := NewCodec()
+	build := func(entries []GossipEntry) any {
+		return &Ping{
+			MessageHeader: MessageHeader{Type: MessageTypePing, SeqNo: 1, SourceID: "local"},
+			Target:        "target",
+			Gossip:        entries,
+		}
+	}
+
+	// The codec describes every field of the message types up front, so the
+	// empty message grows with them. Leave room for a few entries beyond it
+	empty, err := codec.Encode(build(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	maxSize := len(empty) + 200
+
+	got := queue.GetBroadcasts(fitsPacket(codec, maxSize, build))
+	if len(got) == 0 || len(got) == 32 {
+		t.Fatalf("packed %d entries, want a partial packet", len(got))
+	}
+
+	data, err := codec.Encode(build(got))
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if len(data) > maxSize {
+		t.Errorf("encoded size = %d, want <= %d", len(data), maxSize)
+	}
+
+	if queue.Len() != 32 {
+		t.Errorf("Len = %d, want 32 (all entries still under their limit)", queue.Len())
+	}
+}
+
+func TestBroadcastQueue_PackingStopsOncePacketIsFull(t *testing.T) {
+	cfg := testBroadcastConfig()
+	cfg.MaxBroadcast = 500
+	cfg.MaxGossipEntries = 500
+	queue := NewBroadcastQueue(cfg, fixedNodes(10))
+
+	for i := range 500 {
+		queue.Queue(GossipEntry{NodeID: types.NodeID(fmt.Sprintf("node%03d", i)), State: types.StateAlive, Incarnation: 1})
+	}
+
+	calls := 0
+	got := queue.GetBroadcasts(func(entries []GossipEntry) bool {
+		calls++
+		return len(entries) <= 5
+	})
+
+	if len(got) != 5 {
+		t.Errorf("packed %d entries, want 5", len(got))
+	}
+	if calls > 5+maxPackMisses {
+		t.Errorf("fits called %d times, want at most %d", calls, 5+maxPackMisses)
+	}
+}
+
+func TestBroadcastQueue_Reset(t *testing.T) {
+	queue := NewBroadcastQueue(testBroadcastConfig(), fixedNodes(10))
+	queue.Queue(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 1})
+
+	queue.Reset()
+
+	if queue.Len() != 0 {
+		t.Errorf("Len = %d, want 0", queue.Len())
+	}
+}
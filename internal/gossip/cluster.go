@@ -0,0 +1,108 @@
+package gossip
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/michael-martinez-dev/adaptive-hive/internal/config"
+)
+
+var ErrForeignCluster = errors.New("peer belongs to a cluster we do not merge with")
+
+// stamp sets our cluster and team on msg's header
+func (g *gossiper) stamp(msg any) {
+	if m, ok := msg.(headed); ok {
+		h := m.messageHeader()
+		h.ClusterID = g.cfg.Cluster.ID
+		h.Team = g.cfg.Cluster.Team
+	}
+}
+
+// isForeign reports whether h comes from another cluster
+func (g *gossiper) isForeign(h *MessageHeader) bool {
+	return g.isForeignCluster(h.ClusterID)
+}
+
+// isForeignCluster reports whether id names another cluster. Peers and
+// members without a cluster ID are treated as our own
+func (g *gossiper) isForeignCluster(id string) bool {
+	return id != "" && id != g.cfg.Cluster.ID
+}
+
+// admits reports whether the merge policy lets us process a message with
+// header h
+func (g *gossiper) admits(h *MessageHeader) bool {
+	return g.admitsCluster(h.ClusterID)
+}
+
+// admitsCluster reports whether the merge policy lets us merge with members
+// of the cluster id
+func (g *gossiper) admitsCluster(id string) bool {
+	if !g.isForeignCluster(id) {
+		return true
+	}
+
+	switch g.cfg.Cluster.MergePolicy {
+	case config.MergeAuto:
+		return true
+	case config.MergeAllowList:
+		return slices.Contains(g.cfg.Cluster.AllowedClusters, id)
+	default:
+		return false
+	}
+}
+
+// meetCluster is called for every admitted datagram, after its gossip is
+// merged. The first time a foreign cluster is met, full state is exchanged
+// with the sender so both sides learn the whole combined membership at once
+func (g *gossiper) meetCluster(h *MessageHeader, addr string) {
+	if !g.isForeign(h) {
+		return
+	}
+
+	if g.stream == nil {
+		// Without streams the clusters merge through gossip alone
+		g.clusterMerged(h)
+		return
+	}
+
+	g.clusterMu.Lock()
+	busy := g.mergedClusters[h.ClusterID] || g.syncingClusters[h.ClusterID]
+	if !busy {
+		g.syncingClusters[h.ClusterID] = true
+	}
+	g.clusterMu.Unlock()
+	if busy {
+		return
+	}
+
+	g.goFromReceiveLoop(func() {
+		_ = g.pushPull(addr) // announces the merge on success
+
+		g.clusterMu.Lock()
+		delete(g.syncingClusters, h.ClusterID)
+		g.clusterMu.Unlock()
+	})
+}
+
+// clusterMerged publishes a cluster merge event the first time we merge
+// with h's cluster
+func (g *gossiper) clusterMerged(h *MessageHeader) {
+	if !g.isForeign(h) {
+		return
+	}
+
+	g.clusterMu.Lock()
+	first := !g.mergedClusters[h.ClusterID]
+	g.mergedClusters[h.ClusterID] = true
+	g.clusterMu.Unlock()
+
+	if first {
+		g.events.publish(MembershipEvent{
+			Type:    EventClusterMerged,
+			Cluster: h.ClusterID,
+			Team:    h.Team,
+			Members: g.members.AllNodes(),
+		})
+	}
+}
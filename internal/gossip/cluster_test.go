@@ -0,0 +1,192 @@
+package gossip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/michael-martinez-dev/adaptive-hive/internal/config"
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+// --- Helpers --- //
+
+func testClusterConfig(id, cluster, team string) config.Config {
+	cfg := testGossiperConfig(id)
+	cfg.Cluster.ID = cluster
+	cfg.Cluster.Team = team
+	return cfg
+}
+
+// newTeamCluster starts a stream gossiper per id in cluster and joins them
+// to the first one
+func newTeamCluster(t *testing.T, network *TestNetwork, cluster string, cfgs ...config.Config) []Gossiper {
+	t.Helper()
+	gossipers := make([]Gossiper, len(cfgs))
+	for i, cfg := range cfgs {
+		gossipers[i] = newStreamGossiper(t, network, cfg)
+	}
+	for _, g := range gossipers[1:] {
+		if err := g.Join(cfgs[0].Node.ID); err != nil {
+			t.Fatalf("failed to join cluster %s: %v", cluster, err)
+		}
+	}
+	return gossipers
+}
+
+func waitForClusterMerged(t *testing.T, sub Subscription) MembershipEvent {
+	t.Helper()
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case event := <-sub.Events():
+			if event.Type == EventClusterMerged {
+				return event
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for a cluster merge event")
+			return MembershipEvent{}
+		}
+	}
+}
+
+func TestCluster_AutoMerge(t *testing.T) {
+	network := NewTestNetwork()
+	alpha := newTeamCluster(t, network, "alpha",
+		testClusterConfig("node1", "alpha", "red"),
+		testClusterConfig("node2", "alpha", "red"))
+	bravo := newTeamCluster(t, network, "bravo",
+		testClusterConfig("node3", "bravo", "blue"),
+		testClusterConfig("node4", "bravo", "blue"))
+
+	sub := alpha[0].Members().Subscribe(64)
+	defer sub.Close()
+
+	// The teams meet
+	if err := bravo[0].Join("node1"); err != nil {
+		t.Fatalf("clusters failed to merge: %v", err)
+	}
+
+	event := waitForClusterMerged(t, sub)
+	if event.Cluster != "bravo" || event.Team != "blue" {
+		t.Errorf("merged with %s/%s, want bravo/blue", event.Cluster, event.Team)
+	}
+	if len(event.Members) != 4 {
+		t.Errorf("combined membership has %d members, want 4", len(event.Members))
+	}
+
+	all := []types.NodeID{"node1", "node2", "node3", "node4"}
+	for _, g := range append(alpha, bravo...) {
+		waitFor(t, 3*time.Second, func() bool { return knowsAlive(g, all...) }, "clusters to converge")
+	}
+
+	// Further traffic between the clusters does not announce the merge again
+	time.Sleep(5 * testGossiperConfig("node1").FailureDetector.ProbeInterval)
+	for len(sub.Events()) > 0 {
+		if event := <-sub.Events(); event.Type == EventClusterMerged {
+			t.Fatalf("merge with %s announced twice", event.Cluster)
+		}
+	}
+}
+
+func TestCluster_RefuseMerge(t *testing.T) {
+	network := NewTestNetwork()
+	cfg := testClusterConfig("node1", "alpha", "red")
+	cfg.Cluster.MergePolicy = config.MergeRefuse
+	g1 := newStreamGossiper(t, network, cfg)
+	g3 := newStreamGossiper(t, network, testClusterConfig("node3", "bravo", "blue"))
+
+	if err := g3.Join("node1"); err != ErrNoSeedsResponded {
+		t.Errorf("err = %v, want ErrNoSeedsResponded", err)
+	}
+
+	time.Sleep(5 * cfg.FailureDetector.ProbeInterval)
+	if _, exists := g1.Members().GetNode("node3"); exists {
+		t.Error("node1 learned about a node from a refused cluster")
+	}
+}
+
+func TestCluster_RefusedMembersRelayedByOwnCluster(t *testing.T) {
+	network := NewTestNetwork()
+	cfg := testClusterConfig("node1", "alpha", "red")
+	cfg.Cluster.MergePolicy = config.MergeRefuse
+	g1 := newStreamGossiper(t, network, cfg)
+	g2 := newStreamGossiper(t, network, testClusterConfig("node2", "alpha", "red"))
+	if err := g2.Join("node1"); err != nil {
+		t.Fatalf("node2 failed to join: %v", err)
+	}
+
+	// node2 merges with bravo and passes node3 along in gossip and syncs
+	g3 := newStreamGossiper(t, network, testClusterConfig("node3", "bravo", "blue"))
+	if err := g3.Join("node2"); err != nil {
+		t.Fatalf("node3 failed to join node2: %v", err)
+	}
+	waitFor(t, 3*time.Second, func() bool { return knowsAlive(g2, "node1", "node3") }, "node2 to learn both clusters")
+
+	time.Sleep(10 * cfg.FailureDetector.ProbeInterval)
+	if _, exists := g1.Members().GetNode("node3"); exists {
+		t.Error("node1 learned about a node from a refused cluster through its own cluster")
+	}
+}
+
+func TestCluster_AllowList(t *testing.T) {
+	network := NewTestNetwork()
+	cfg := testClusterConfig("node1", "alpha", "red")
+	cfg.Cluster.MergePolicy = config.MergeAllowList
+	cfg.Cluster.AllowedClusters = []string{"bravo"}
+	g1 := newStreamGossiper(t, network, cfg)
+
+	g3 := newStreamGossiper(t, network, testClusterConfig("node3", "bravo", "blue"))
+	g5 := newStreamGossiper(t, network, testClusterConfig("node5", "charlie", "green"))
+
+	if err := g3.Join("node1"); err != nil {
+		t.Errorf("allowed cluster failed to merge: %v", err)
+	}
+	if err := g5.Join("node1"); err != ErrNoSeedsResponded {
+		t.Errorf("err = %v from a cluster not on the list, want ErrNoSeedsResponded", err)
+	}
+
+	waitFor(t, 3*time.Second, func() bool { return knowsAlive(g1, "node3") }, "node1 to learn node3")
+	if _, exists := g1.Members().GetNode("node5"); exists {
+		t.Error("node1 learned about a node from a cluster not on the list")
+	}
+}
+
+func TestCluster_SameClusterIsNotAMerge(t *testing.T) {
+	network := NewTestNetwork()
+	g1 := newStreamGossiper(t, network, testClusterConfig("node1", "alpha", "red"))
+	sub := g1.Members().Subscribe(64)
+	defer sub.Close()
+
+	g2 := newStreamGossiper(t, network, testClusterConfig("node2", "alpha", "blue"))
+	if err := g2.Join("node1"); err != nil {
+		t.Fatalf("node2 failed to join: %v", err)
+	}
+	waitFor(t, 3*time.Second, func() bool { return knowsAlive(g1, "node2") }, "node1 to learn node2")
+
+	for len(sub.Events()) > 0 {
+		if event := <-sub.Events(); event.Type == EventClusterMerged {
+			t.Errorf("joining the same cluster announced a merge with %s", event.Cluster)
+		}
+	}
+}
+
+func TestCluster_MergeWithoutStreams(t *testing.T) {
+	network := NewTestNetwork()
+	g1 := newTestGossiperWithConfig(t, testClusterConfig("node1", "alpha", "red"), network.NewTransport("node1"), NewCodec())
+	sub := g1.Members().Subscribe(64)
+	defer sub.Close()
+
+	g3 := newTestGossiperWithConfig(t, testClusterConfig("node3", "bravo", "blue"), network.NewTransport("node3"), NewCodec())
+	if err := g3.Join("node1"); err != nil {
+		t.Fatalf("node3 failed to join: %v", err)
+	}
+
+	event := waitForClusterMerged(t, sub)
+	if event.Cluster != "bravo" {
+		t.Errorf("merged with %s, want bravo", event.Cluster)
+	}
+	if !sameIDs(event.Members, []types.NodeID{"node1", "node3"}) {
+		t.Errorf("event members = %v, want [node1 node3]", nodeIDs(event.Members))
+	}
+	waitFor(t, 3*time.Second, func() bool { return knowsAlive(g1, "node3") }, "node1 to learn node3")
+}
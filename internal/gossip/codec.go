@@ -4,29 +4,62 @@ import (
 	"bytes"
 	"encoding/gob"
 	"errors"
+	"fmt"
 )
 
+var (
+	ErrUnknownMessageType = errors.New("unknown message type")
+	ErrEmptyPayload       = errors.New("payload cannot be empty")
+)
+
+// UnknownMessageTypeError is returned by Decode for a well-formed message of
+// a type this codec does not know, typically sent by a newer peer. Callers
+// can skip such messages and carry on. It matches ErrUnknownMessageType
+// with errors.Is
+type UnknownMessageTypeError struct {
+	Type MessageType
+}
+
+func (e *UnknownMessageTypeError) Error() string {
+	return fmt.Sprintf("unknown message type %d", e.Type)
+}
+
+func (e *UnknownMessageTypeError) Is(target error) bool {
+	return target == ErrUnknownMessageType
+}
+
 type Codec interface {
 	Encode(msg any) ([]byte, error)
 	Decode(data []byte) (any, error)
 }
 
 type codec struct {
+	opts codecOptions
 }
 
-func NewCodec() Codec {
-	return &codec{}
+func NewCodec(opts ...CodecOption) Codec {
+	return &codec{opts: newCodecOptions(opts)}
 }
 
 type envelope struct {
 	Type    MessageType
 	Payload []byte
+	Flags   uint8 // older peers leave it zero
 }
 
+// envelope flags
+const (
+	payloadCompressed uint8 = 1 << iota
+)
+
 func (c *codec) Encode(msg any) ([]byte, error) {
+	return c.encode(msg, true)
+}
+
+func (c *codec) encode(msg any, compressible bool) ([]byte, error) {
 	msgType := getMessageType(msg)
 	if msgType == 0 {
-		return nil, errors.New("unknown message type")
+		return nil, fmt.Errorf("%w: %T", ErrUnknownMessageType, msg)
 	}
 
 	// Encode message payload
@@ -38,7 +71,7 @@ func (c *codec) Encode(msg any) ([]byte, error) {
 	}
 
 	if len(payloadBuff.Bytes()) == 0 {
-		return nil, errors.New("payload cannot be empty")
+		return nil, ErrEmptyPayload
 	}
 
 	// wrap payload in envelope and Encode
@@ -48,6 +81,12 @@ func (c *codec) Encode(msg any) ([]byte, error) {
 		Type:    msgType,
 		Payload: payloadBuff.Bytes(),
 	}
+	if compressible && c.opts.shouldCompress(len(env.Payload)) {
+		if compressed, ok := compress(env.Payload); ok {
+			env.Payload = compressed
+			env.Flags |= payloadCompressed
+		}
+	}
 
 	if err := envEnc.Encode(env); err != nil {
 		return nil, err
@@ -68,6 +107,15 @@ func (c *codec) Decode(data []byte) (any, error) {
 		return nil, err
 	}
 
+	if env.Flags&^payloadCompressed != 0 {
+		return nil, fmt.Errorf("unknown envelope flags %#x", env.Flags)
+	}
+	if env.Flags&payloadCompressed != 0 {
+		if env.Payload, err = decompress(env.Payload); err != nil {
+			return nil, err
+		}
+	}
+
 	payloadDec := gob.NewDecoder(bytes.NewReader(env.Payload))
 	if err := payloadDec.Decode(msg); err != nil {
 		return nil, err
@@ -84,6 +132,18 @@ func getMessageType(msg any) MessageType {
 		return MessageTypeAck
 	case *PingReq:
 		return MessageTypePingReq
+	case *Nack:
+		return MessageTypeNack
+	case *Leave:
+		return MessageTypeLeave
+	case *Sync:
+		return MessageTypeSync
+	case *SyncResponse:
+		return MessageTypeSyncResponse
+	case *Compound:
+		return MessageTypeCompound
+	case *Gossip:
+		return MessageTypeGossip
 	default:
 		return 0
 	}
@@ -97,7 +157,19 @@ func newMessageByType(messageType MessageType) (any, error) {
 		return &Ack{}, nil
 	case MessageTypePingReq:
 		return &PingReq{}, nil
+	case MessageTypeNack:
+		return &Nack{}, nil
+	case MessageTypeLeave:
+		return &Leave{}, nil
+	case MessageTypeSync:
+		return &Sync{}, nil
+	case MessageTypeSyncResponse:
+		return &SyncResponse{}, nil
+	case MessageTypeCompound:
+		return &Compound{}, nil
+	case MessageTypeGossip:
+		return &Gossip{}, nil
 	default:
-		return nil, errors.New("unknown message type")
+		return nil, &UnknownMessageTypeError{Type: messageType}
 	}
 }
@@ -1,6 +1,10 @@
 package gossip
 
 import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
@@ -256,3 +260,229 @@ func TestCodec_EmptyData(t *testing.T) {
 		t.Fatalf("Encode should have failed but didn't")
 	}
 }
+
+func TestCodec_RoundtripNack(t *testing.T) {
+	// Create a Nack, encode it, decode it, compare
+	codec := NewCodec()
+
+	original := &Nack{
+		MessageHeader: MessageHeader{
+			Version:  1,
+			Type:     MessageTypeNack,
+			SeqNo:    42,
+			SourceID: "node1",
+		},
+	}
+
+	data, err := codec.Encode(original)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	decoded, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	nack, ok := decoded.(*Nack)
+	if !ok {
+		t.Fatalf("decoded type = %T, want *Nack", decoded)
+	}
+
+	if nack.SeqNo != original.SeqNo {
+		t.Errorf("SeqNo = %d, want %d", nack.SeqNo, original.SeqNo)
+	}
+
+	if nack.SourceID != original.SourceID {
+		t.Errorf("SourceID = %s, want %s", nack.SourceID, original.SourceID)
+	}
+}
+
+func TestCodec_RoundtripLeave(t *testing.T) {
+	// Create a Leave, encode it, decode it, compare
+	codec := NewCodec()
+
+	original := &Leave{
+		MessageHeader: MessageHeader{
+			Version:  1,
+			Type:     MessageTypeLeave,
+			SeqNo:    42,
+			SourceID: "node1",
+		},
+		Incarnation: 7,
+	}
+
+	data, err := codec.Encode(original)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	decoded, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	leave, ok := decoded.(*Leave)
+	if !ok {
+		t.Fatalf("decoded type = %T, want *Leave", decoded)
+	}
+
+	if leave.SourceID != original.SourceID {
+		t.Errorf("SourceID = %s, want %s", leave.SourceID, original.SourceID)
+	}
+
+	if leave.Incarnation != original.Incarnation {
+		t.Errorf("Incarnation = %d, want %d", leave.Incarnation, original.Incarnation)
+	}
+}
+
+func TestCodec_RoundtripSync(t *testing.T) {
+	// Create a Sync and a SyncResponse, encode them, decode them, compare
+	codec := NewCodec()
+
+	nodes := []GossipEntry{
+		{NodeID: "node1", Address: "10.0.0.1:1234", State: types.StateAlive, Incarnation: 5},
+		{NodeID: "node2", Address: "10.0.0.2:1234", State: types.StateLeft, Incarnation: 2},
+	}
+
+	for _, original := range []any{
+		&Sync{MessageHeader: MessageHeader{Type: MessageTypeSync, SeqNo: 42, SourceID: "node1"}, Nodes: nodes},
+		&SyncResponse{MessageHeader: MessageHeader{Type: MessageTypeSyncResponse, SeqNo: 42, SourceID: "node2"}, Nodes: nodes},
+	} {
+		data, err := codec.Encode(original)
+		if err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+
+		decoded, err := codec.Decode(data)
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+
+		switch msg := decoded.(type) {
+		case *Sync:
+			if len(msg.Nodes) != len(nodes) {
+				t.Errorf("Sync nodes = %d, want %d", len(msg.Nodes), len(nodes))
+			}
+		case *SyncResponse:
+			if len(msg.Nodes) != len(nodes) {
+				t.Errorf("SyncResponse nodes = %d, want %d", len(msg.Nodes), len(nodes))
+			}
+		default:
+			t.Fatalf("decoded type = %T, want %T", decoded, original)
+		}
+	}
+}
+
+type messageCase struct {
+	name    string
+	msgType MessageType
+	msg     any
+}
+
+// allMessages returns a fully populated message of every type
+func allMessages() []messageCase {
+	header := func(msgType MessageType) MessageHeader {
+		return MessageHeader{
+			Version:   1,
+			Type:      msgType,
+			SeqNo:     7,
+			SourceID:  "node1",
+			Checksum:  0xdeadbeef,
+			ClusterID: "blue",
+			Team:      "infra",
+		}
+	}
+	gossip := []GossipEntry{
+		{NodeID: "node2", Address: "10.0.0.2:1234", State: types.StateSuspect, Incarnation: 3, Timestamp: 99, Suspector: "node3", ClusterID: "blue"},
+		{
+			NodeID: "node3", Address: "10.0.0.3:1234", State: types.StateAlive, Incarnation: 4, MetadataIncarnation: 1,
+			Metadata: &types.NodeMetadata{
+				Resources: types.Resources{CPUMillis: 2000, MemoryBytes: 1 << 30, DiskBytes: 1 << 40},
+				Labels:    map[string]string{"zone": "a", "rack": "r1"},
+				Priority:  -2,
+				Protocol:  types.ProtocolRange{Min: 1, Max: 2},
+				Compound:  true,
+				Gossip:    true,
+			},
+		},
+	}
+
+	return []messageCase{
+		{"ping", MessageTypePing, &Ping{MessageHeader: header(MessageTypePing), Target: "node2", Gossip: gossip}},
+		{"ping-req", MessageTypePingReq, &PingReq{Ping: Ping{MessageHeader: header(MessageTypePingReq), Target: "node2", Gossip: gossip}, TargetAdder: "10.0.0.2:1234"}},
+		{"ack", MessageTypeAck, &Ack{MessageHeader: header(MessageTypeAck), Gossip: gossip}},
+		{"nack", MessageTypeNack, &Nack{MessageHeader: header(MessageTypeNack)}},
+		{"sync", MessageTypeSync, &Sync{MessageHeader: header(MessageTypeSync), Nodes: gossip}},
+		{"sync-response", MessageTypeSyncResponse, &SyncResponse{MessageHeader: header(MessageTypeSyncResponse), Nodes: gossip}},
+		{"leave", MessageTypeLeave, &Leave{MessageHeader: header(MessageTypeLeave), Incarnation: 4}},
+		{"compound", MessageTypeCompound, &Compound{MessageHeader: header(MessageTypeCompound), Messages: [][]byte{{1, 2, 3}, {4}}}},
+		{"gossip", MessageTypeGossip, &Gossip{MessageHeader: header(MessageTypeGossip), Gossip: gossip}},
+	}
+}
+
+// testRoundtripAll checks that every message type survives a roundtrip
+// through codec with all fields intact
+func testRoundtripAll(t *testing.T, codec Codec) {
+	t.Helper()
+
+	covered := make(map[MessageType]bool)
+	for _, tt := range allMessages() {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getMessageType(tt.msg); got != tt.msgType {
+				t.Fatalf("getMessageType = %v, want %v", got, tt.msgType)
+			}
+
+			data, err := codec.Encode(tt.msg)
+			if err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+			decoded, err := codec.Decode(data)
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, tt.msg) {
+				t.Errorf("decoded = %+v, want %+v", decoded, tt.msg)
+			}
+		})
+		covered[tt.msgType] = true
+	}
+
+	for msgType := MessageType(1); msgType.String() != "unknown"; msgType++ {
+		if !covered[msgType] {
+			t.Errorf("message type %v has no roundtrip case", msgType)
+		}
+	}
+}
+
+func TestCodec_RoundtripAllTypes(t *testing.T) {
+	testRoundtripAll(t, NewCodec())
+}
+
+func TestCodec_DecodeUnknownType(t *testing.T) {
+	// A well-formed envelope of a type we do not know comes back as a
+	// typed error the caller can skip
+	codec := NewCodec()
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(envelope{Type: 200, Payload: []byte("from the future")}); err != nil {
+		t.Fatalf("encoding envelope: %v", err)
+	}
+
+	decoded, err := codec.Decode(buf.Bytes())
+	if decoded != nil {
+		t.Errorf("decoded = %+v, want nil", decoded)
+	}
+	if !errors.Is(err, ErrUnknownMessageType) {
+		t.Fatalf("err = %v, want ErrUnknownMessageType", err)
+	}
+	var unknown *UnknownMessageTypeError
+	if !errors.As(err, &unknown) || unknown.Type != 200 {
+		t.Errorf("err = %#v, want UnknownMessageTypeError for type 200", err)
+	}
+
+	// Corrupt data is not an unknown type
+	if _, err := codec.Decode([]byte{0xff, 0x00, 0x13}); errors.Is(err, ErrUnknownMessageType) {
+		t.Errorf("corrupt data reported as unknown type: %v", err)
+	}
+}
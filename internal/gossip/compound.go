@@ -0,0 +1,80 @@
+package gossip
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var ErrNestedCompound = errors.New("compound message inside a compound message")
+
+// DecodeMessages decodes a datagram holding either a single message or a
+// Compound, returning the messages it carries in order. Parts of a type we
+// do not know are skipped, any other bad part fails the whole datagram
+func DecodeMessages(codec Codec, data []byte) ([]any, error) {
+	msg, err := codec.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	compound, ok := msg.(*Compound)
+	if !ok {
+		return []any{msg}, nil
+	}
+
+	msgs := make([]any, 0, len(compound.Messages))
+	for i, part := range compound.Messages {
+		msg, err := codec.Decode(part)
+		if errors.Is(err, ErrUnknownMessageType) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("compound part %d: %w", i, err)
+		}
+		if _, nested := msg.(*Compound); nested {
+			return nil, ErrNestedCompound
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// packMessages packs encoded messages into as few datagrams of at most
+// maxSize bytes as it can, keeping their order. A message that does not
+// share a datagram is sent as is, the others are wrapped in a Compound
+// with header h
+func packMessages(codec Codec, h MessageHeader, parts [][]byte, maxSize int) ([][]byte, error) {
+	h.Type = MessageTypeCompound
+
+	var datagrams [][]byte
+	var batch [][]byte
+	var packed []byte // batch encoded, if it holds more than one part
+
+	flush := func() {
+		switch len(batch) {
+		case 0:
+		case 1:
+			datagrams = append(datagrams, batch[0])
+		default:
+			datagrams = append(datagrams, packed)
+		}
+		batch, packed = nil, nil
+	}
+
+	for _, part := range parts {
+		if len(batch) > 0 {
+			candidate := append(slices.Clip(batch), part)
+			data, err := codec.Encode(&Compound{MessageHeader: h, Messages: candidate})
+			if err != nil {
+				return nil, err
+			}
+			if len(data) <= maxSize {
+				batch, packed = candidate, data
+				continue
+			}
+			flush()
+		}
+		batch = append(batch, part)
+	}
+	flush()
+	return datagrams, nil
+}
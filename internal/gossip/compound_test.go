@@ -0,0 +1,272 @@
+package gossip
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+func nack(seq uint32) *Nack {
+	return &Nack{MessageHeader: MessageHeader{Type: MessageTypeNack, SeqNo: seq, SourceID: "node1"}}
+}
+
+func TestDecodeMessages_Single(t *testing.T) {
+	codec := NewBinaryCodec()
+
+	msgs, err := DecodeMessages(codec, mustEncode(t, codec, nack(1)))
+	if err != nil {
+		t.Fatalf("DecodeMessages failed: %v", err)
+	}
+	if len(msgs) != 1 || !reflect.DeepEqual(msgs[0], nack(1)) {
+		t.Errorf("msgs = %+v, want the nack", msgs)
+	}
+}
+
+func TestDecodeMessages_Compound(t *testing.T) {
+	for name, codec := range map[string]Codec{"gob": NewCodec(), "binary": NewBinaryCodec()} {
+		t.Run(name, func(t *testing.T) {
+			compound := &Compound{
+				MessageHeader: MessageHeader{Type: MessageTypeCompound},
+				Messages:      [][]byte{mustEncode(t, codec, nack(1)), mustEncode(t, codec, nack(2))},
+			}
+
+			msgs, err := DecodeMessages(codec, mustEncode(t, codec, compound))
+			if err != nil {
+				t.Fatalf("DecodeMessages failed: %v", err)
+			}
+			if want := []any{nack(1), nack(2)}; !reflect.DeepEqual(msgs, want) {
+				t.Errorf("msgs = %+v, want %+v", msgs, want)
+			}
+		})
+	}
+}
+
+func TestDecodeMessages_SkipsUnknownParts(t *testing.T) {
+	codec := NewCodec()
+
+	var unknown bytes.Buffer
+	if err := gob.NewEncoder(&unknown).Encode(envelope{Type: 200, Payload: []byte("from the future")}); err != nil {
+		t.Fatalf("encoding envelope: %v", err)
+	}
+	compound := &Compound{
+		MessageHeader: MessageHeader{Type: MessageTypeCompound},
+		Messages:      [][]byte{unknown.Bytes(), mustEncode(t, codec, nack(2))},
+	}
+
+	msgs, err := DecodeMessages(codec, mustEncode(t, codec, compound))
+	if err != nil {
+		t.Fatalf("DecodeMessages failed: %v", err)
+	}
+	if want := []any{nack(2)}; !reflect.DeepEqual(msgs, want) {
+		t.Errorf("msgs = %+v, want %+v", msgs, want)
+	}
+}
+
+func TestDecodeMessages_RejectsBadParts(t *testing.T) {
+	codec := NewBinaryCodec()
+	inner := mustEncode(t, codec, &Compound{MessageHeader: MessageHeader{Type: MessageTypeCompound}})
+
+	tests := map[string]struct {
+		part []byte
+		want error
+	}{
+		"nested":  {inner, ErrNestedCompound},
+		"corrupt": {[]byte{binaryMagic, 0xff}, ErrMalformedMessage},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			compound := &Compound{
+				MessageHeader: MessageHeader{Type: MessageTypeCompound},
+				Messages:      [][]byte{mustEncode(t, codec, nack(1)), tt.part},
+			}
+			if _, err := DecodeMessages(codec, mustEncode(t, codec, compound)); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackMessages(t *testing.T) {
+	codec := NewBinaryCodec()
+	header := MessageHeader{SourceID: "node1"}
+
+	var parts [][]byte
+	for seq := uint32(1); seq <= 20; seq++ {
+		parts = append(parts, mustEncode(t, codec, nack(seq)))
+	}
+	big := mustEncode(t, codec, &Ping{MessageHeader: MessageHeader{Type: MessageTypePing}, Target: string(make([]byte, 100))})
+	parts = append(parts, big)
+
+	const maxSize = 64
+	datagrams, err := packMessages(codec, header, parts, maxSize)
+	if err != nil {
+		t.Fatalf("packMessages failed: %v", err)
+	}
+	if len(datagrams) >= len(parts) {
+		t.Errorf("packed %d messages into %d datagrams", len(parts), len(datagrams))
+	}
+
+	var unpacked []any
+	for _, data := range datagrams {
+		if len(data) > maxSize && !bytes.Equal(data, big) {
+			t.Errorf("datagram of %d bytes exceeds %d", len(data), maxSize)
+		}
+		msgs, err := DecodeMessages(codec, data)
+		if err != nil {
+			t.Fatalf("DecodeMessages failed: %v", err)
+		}
+		unpacked = append(unpacked, msgs...)
+	}
+
+	if len(unpacked) != len(parts) {
+		t.Fatalf("unpacked %d messages, want %d", len(unpacked), len(parts))
+	}
+	for i, msg := range unpacked[:20] {
+		if !reflect.DeepEqual(msg, nack(uint32(i+1))) {
+			t.Errorf("message %d = %+v, want nack %d", i, msg, i+1)
+		}
+	}
+	if !bytes.Equal(datagrams[len(datagrams)-1], big) {
+		t.Error("oversized message was not sent on its own")
+	}
+}
+
+// recordingTransport records every datagram sent through it
+type recordingTransport struct {
+	Transport
+
+	mu    sync.Mutex
+	sent  [][]byte
+	addrs []string
+}
+
+func (t *recordingTransport) SendTo(addr string, msg []byte) error {
+	t.mu.Lock()
+	t.sent = append(t.sent, msg)
+	t.addrs = append(t.addrs, addr)
+	t.mu.Unlock()
+	return t.Transport.SendTo(addr, msg)
+}
+
+func (t *recordingTransport) datagrams() [][]byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([][]byte(nil), t.sent...)
+}
+
+func (t *recordingTransport) datagramsTo(addr string) [][]byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var sent [][]byte
+	for i, data := range t.sent {
+		if t.addrs[i] == addr {
+			sent = append(sent, data)
+		}
+	}
+	return sent
+}
+
+func startRecordingGossiper(t *testing.T, network *TestNetwork, codec Codec) (*gossiper, *recordingTransport) {
+	t.Helper()
+
+	cfg := testGossiperConfig("node1")
+	cfg.Gossip.BatchDelay = 50 * time.Millisecond
+	transport := &recordingTransport{Transport: network.NewTransport("node1")}
+	return newTestGossiperWithConfig(t, cfg, transport, codec), transport
+}
+
+// addPeer makes node2 a member of g's cluster
+func addPeer(g *gossiper, meta types.NodeMetadata) {
+	_ = g.members.Merge(GossipEntry{NodeID: "node2", Address: "node2", State: types.StateAlive, Incarnation: 1, Metadata: &meta})
+}
+
+// nacksPerDatagram counts the nacks in each datagram g sent to node2 that
+// carries any. Probes of node2 may share a datagram with them
+func nacksPerDatagram(t *testing.T, g *gossiper, transport *recordingTransport) []int {
+	t.Helper()
+
+	var counts []int
+	for _, data := range transport.datagramsTo("node2") {
+		msgs, err := DecodeMessages(g.codec, data)
+		if err != nil {
+			t.Fatalf("DecodeMessages failed: %v", err)
+		}
+		n := 0
+		for _, msg := range msgs {
+			if _, ok := msg.(*Nack); ok {
+				n++
+			}
+		}
+		if n > 0 {
+			counts = append(counts, n)
+		}
+	}
+	return counts
+}
+
+func sum(counts []int) int {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return total
+}
+
+func TestGossiper_BatchesMessagesToSamePeer(t *testing.T) {
+	for name, codec := range map[string]Codec{"gob": NewCodec(), "binary": NewBinaryCodec()} {
+		t.Run(name, func(t *testing.T) {
+			network := NewTestNetwork()
+			g, transport := startRecordingGossiper(t, network, codec)
+			network.NewTransport("node2")
+			addPeer(g, types.NodeMetadata{Protocol: codecVersions(codec), Compound: true})
+			if local, _ := g.members.LocalNode(); !local.Metadata.Compound {
+				t.Error("node1 does not advertise that it unpacks compound messages")
+			}
+
+			for seq := uint32(1); seq <= 3; seq++ {
+				if err := g.send("node2", nack(seq)); err != nil {
+					t.Fatalf("send failed: %v", err)
+				}
+			}
+			waitFor(t, time.Second, func() bool { return len(nacksPerDatagram(t, g, transport)) > 0 }, "the batch to be sent")
+
+			if counts := nacksPerDatagram(t, g, transport); !slices.Equal(counts, []int{3}) {
+				t.Errorf("nacks per datagram = %v, want all 3 in one", counts)
+			}
+		})
+	}
+}
+
+func TestGossiper_NoCompoundsForPeersThatCannotUnpackThem(t *testing.T) {
+	// Peers that do not advertise it may predate compound messages
+	network := NewTestNetwork()
+	g, transport := startRecordingGossiper(t, network, NewBinaryCodec())
+	network.NewTransport("node2")
+	addPeer(g, types.NodeMetadata{Protocol: codecVersions(g.codec)})
+
+	for seq := uint32(1); seq <= 3; seq++ {
+		if err := g.send("node2", nack(seq)); err != nil {
+			t.Fatalf("send failed: %v", err)
+		}
+	}
+
+	waitFor(t, time.Second, func() bool { return sum(nacksPerDatagram(t, g, transport)) == 3 }, "the nacks to be sent")
+
+	for _, data := range transport.datagramsTo("node2") {
+		msg, err := g.codec.Decode(data)
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if _, ok := msg.(*Compound); ok {
+			t.Fatal("node1 sent a compound message to a peer that cannot unpack it")
+		}
+	}
+}
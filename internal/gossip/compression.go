@@ -0,0 +1,136 @@
+package gossip
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+var ErrDecompressedTooLarge = errors.New("compressed payload expands too far")
+
+// maxDecompressedSize bounds what a compressed payload may expand to, so a
+// small datagram cannot make us allocate without limit
+const maxDecompressedSize = 64 << 20
+
+// CodecOption configures optional codec behaviour
+type CodecOption func(*codecOptions)
+
+type codecOptions struct {
+	compressThreshold int // zero disables compression
+}
+
+// WithCompression compresses payloads of at least threshold bytes with
+// flate, whenever that makes them smaller. Every codec decodes compressed
+// payloads, whether or not it compresses its own
+func WithCompression(threshold int) CodecOption {
+	return func(o *codecOptions) {
+		o.compressThreshold = max(threshold, 0)
+	}
+}
+
+func newCodecOptions(opts []CodecOption) codecOptions {
+	var o codecOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// shouldCompress reports whether a payload of size bytes is worth trying
+// to compress
+func (o codecOptions) shouldCompress(size int) bool {
+	return o.compressThreshold > 0 && size >= o.compressThreshold
+}
+
+// compressingCodec is implemented by codecs that can leave compression
+// out for a single message, for peers that should not get it
+type compressingCodec interface {
+	encode(msg any, compress bool) ([]byte, error)
+}
+
+var flateWriters = sync.Pool{
+	New: func() any {
+		w, _ := flate.NewWriter(nil, flate.BestSpeed) // cannot fail for a valid level
+		return w
+	},
+}
+
+// compress returns data compressed, or false if that does not make it any
+// smaller
+func compress(data []byte) ([]byte, bool) {
+	var buf bytes.Buffer
+	w := flateWriters.Get().(*flate.Writer)
+	defer flateWriters.Put(w)
+
+	w.Reset(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, false
+	}
+	if err := w.Close(); err != nil {
+		return nil, false
+	}
+	if buf.Len() >= len(data) {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
+
+// decompress reverses compress
+func decompress(data []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+
+	out, err := io.ReadAll(io.LimitReader(r, maxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > maxDecompressedSize {
+		return nil, ErrDecompressedTooLarge
+	}
+	return out, nil
+}
+
+// peerCodec encodes for a single peer, leaving compression out unless the
+// peer should get it
+type peerCodec struct {
+	Codec
+	compress bool
+}
+
+func (c peerCodec) Encode(msg any) ([]byte, error) {
+	if cc, ok := c.Codec.(compressingCodec); ok {
+		return cc.encode(msg, c.compress)
+	}
+	return c.Codec.Encode(msg)
+}
+
+// codecFor returns the codec to encode messages for the peer at addr
+func (g *gossiper) codecFor(addr string) Codec {
+	return g.codecWith(g.members.GetNodeByAddress(addr))
+}
+
+// codecWith returns the codec to encode messages for node, if known
+func (g *gossiper) codecWith(node types.Node, known bool) Codec {
+	return peerCodec{Codec: g.codec, compress: known && g.compressFor(node)}
+}
+
+// compressFor reports whether messages to node may be compressed: it has
+// to say it can decode them, and match cfg.Compression if that names any
+// peers
+func (g *gossiper) compressFor(node types.Node) bool {
+	if !node.Metadata.Compression {
+		return false
+	}
+
+	peers := g.cfg.Compression.Peers
+	if len(peers) == 0 && g.compressSelector == nil {
+		return true
+	}
+	return slices.Contains(peers, string(node.ID)) ||
+		g.compressSelector != nil && g.compressSelector.Matches(node.Metadata.Labels)
+}
@@ -0,0 +1,246 @@
+package gossip
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+// largeSync is a membership dump big and repetitive enough to compress well
+func largeSync() *Sync {
+	msg := &Sync{MessageHeader: MessageHeader{Type: MessageTypeSync, SourceID: "node1"}}
+	for i := 0; i < 50; i++ {
+		msg.Nodes = append(msg.Nodes, GossipEntry{
+			NodeID:      types.NodeID(fmt.Sprintf("node%d", i)),
+			Address:     fmt.Sprintf("10.0.0.%d:7946", i),
+			State:       types.StateAlive,
+			Incarnation: 1,
+			Metadata:    &types.NodeMetadata{Labels: map[string]string{"zone": "eu-west-1a", "link": "satellite"}},
+		})
+	}
+	return msg
+}
+
+// isCompressed reports whether data, or any part of it if it is a
+// compound, was sent compressed
+func isCompressed(t *testing.T, data []byte) bool {
+	t.Helper()
+
+	if data[0] == binaryMagic {
+		if data[3]&frameCompressed != 0 {
+			return true
+		}
+	} else {
+		var env envelope
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&env); err != nil {
+			t.Fatalf("decoding envelope: %v", err)
+		}
+		if env.Flags&payloadCompressed != 0 {
+			return true
+		}
+	}
+
+	msg, err := NewCodec().Decode(data)
+	if data[0] == binaryMagic {
+		msg, err = NewBinaryCodec().Decode(data)
+	}
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if compound, ok := msg.(*Compound); ok {
+		for _, part := range compound.Messages {
+			if isCompressed(t, part) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestCompression_Roundtrip(t *testing.T) {
+	codecs := map[string]func(...CodecOption) Codec{"gob": NewCodec, "binary": NewBinaryCodec}
+
+	for name, newCodec := range codecs {
+		t.Run(name, func(t *testing.T) {
+			plain, compressing := newCodec(), newCodec(WithCompression(256))
+
+			original := largeSync()
+			uncompressed := mustEncode(t, plain, original)
+			compressed := mustEncode(t, compressing, original)
+			if !isCompressed(t, compressed) {
+				t.Fatal("large message was not compressed")
+			}
+			if len(compressed)*2 > len(uncompressed) {
+				t.Errorf("compressed = %d bytes, uncompressed = %d, want at least 2x smaller", len(compressed), len(uncompressed))
+			}
+
+			// Receivers decode transparently, compressing or not
+			for _, receiver := range []Codec{plain, compressing} {
+				decoded, err := receiver.Decode(compressed)
+				if err != nil {
+					t.Fatalf("Decode failed: %v", err)
+				}
+				if !reflect.DeepEqual(decoded, original) {
+					t.Errorf("decoded = %+v, want %+v", decoded, original)
+				}
+			}
+		})
+	}
+}
+
+func TestCompression_BelowThreshold(t *testing.T) {
+	codec := NewBinaryCodec(WithCompression(256))
+
+	small := mustEncode(t, codec, nack(1))
+	if isCompressed(t, small) {
+		t.Error("message below the threshold was compressed")
+	}
+	if !bytes.Equal(small, mustEncode(t, NewBinaryCodec(), nack(1))) {
+		t.Error("message below the threshold differs from the uncompressed encoding")
+	}
+}
+
+func TestCompression_LeftOutPerMessage(t *testing.T) {
+	codec, err := NewVersionedCodec(ProtocolV1, ProtocolV2, WithCompression(256))
+	if err != nil {
+		t.Fatalf("NewVersionedCodec failed: %v", err)
+	}
+
+	for _, version := range []uint8{ProtocolV1, ProtocolV2} {
+		msg := largeSync()
+		msg.Version = version
+
+		if !isCompressed(t, mustEncode(t, peerCodec{Codec: codec, compress: true}, msg)) {
+			t.Errorf("v%d: message was not compressed", version)
+		}
+		if isCompressed(t, mustEncode(t, peerCodec{Codec: codec, compress: false}, msg)) {
+			t.Errorf("v%d: message was compressed for a peer that should not get it", version)
+		}
+	}
+}
+
+func TestCompression_RejectsBombs(t *testing.T) {
+	// A small datagram must not expand without limit
+	bomb, ok := compress(make([]byte, maxDecompressedSize+1))
+	if !ok {
+		t.Fatal("zeros did not compress")
+	}
+	data := append([]byte{binaryMagic, binaryVersion, byte(MessageTypeNack), frameCompressed}, bomb...)
+
+	if _, err := NewBinaryCodec().Decode(data); !errors.Is(err, ErrMalformedMessage) {
+		t.Errorf("err = %v, want ErrMalformedMessage", err)
+	}
+}
+
+func TestCompression_UnknownEnvelopeFlags(t *testing.T) {
+	var buf bytes.Buffer
+	payload := mustEncode(t, NewCodec(), nack(1)) // any bytes, never decoded
+	if err := gob.NewEncoder(&buf).Encode(envelope{Type: MessageTypeNack, Payload: payload, Flags: 0x80}); err != nil {
+		t.Fatalf("encoding envelope: %v", err)
+	}
+
+	if _, err := NewCodec().Decode(buf.Bytes()); err == nil {
+		t.Error("Decode accepted unknown envelope flags")
+	}
+}
+
+func TestGossiper_CompressFor(t *testing.T) {
+	capable := func(id string, labels map[string]string) types.Node {
+		return types.Node{ID: types.NodeID(id), Metadata: types.NodeMetadata{Compression: true, Labels: labels}}
+	}
+
+	tests := []struct {
+		name     string
+		peers    []string
+		selector string
+		node     types.Node
+		want     bool
+	}{
+		{"any capable peer", nil, "", capable("node2", nil), true},
+		{"incapable peer", nil, "", types.Node{ID: "node2"}, false},
+		{"listed peer", []string{"node2"}, "", capable("node2", nil), true},
+		{"unlisted peer", []string{"node3"}, "", capable("node2", nil), false},
+		{"matching label", nil, "link in (radio,satellite)", capable("node2", map[string]string{"link": "radio"}), true},
+		{"other label", nil, "link in (radio,satellite)", capable("node2", map[string]string{"link": "fiber"}), false},
+		{"listed but other label", []string{"node2"}, "link=radio", capable("node2", nil), true},
+		{"incapable matching peer", []string{"node2"}, "", types.Node{ID: "node2"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testGossiperConfig("node1")
+			cfg.Compression.Peers = tt.peers
+			cfg.Compression.Selector = tt.selector
+
+			g, err := NewGossiper(cfg, NewTestNetwork().NewTransport("node1"), NewCodec())
+			if err != nil {
+				t.Fatalf("NewGossiper failed: %v", err)
+			}
+			if got := g.(*gossiper).compressFor(tt.node); got != tt.want {
+				t.Errorf("compressFor = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGossiper_InvalidCompressionSelector(t *testing.T) {
+	cfg := testGossiperConfig("node1")
+	cfg.Compression.Selector = "link in (radio"
+
+	if _, err := NewGossiper(cfg, NewTestNetwork().NewTransport("node1"), NewCodec()); !errors.Is(err, ErrInvalidSelector) {
+		t.Errorf("err = %v, want ErrInvalidSelector", err)
+	}
+}
+
+func TestGossiper_CompressesForSelectedPeers(t *testing.T) {
+	network := NewTestNetwork()
+
+	cfg := testGossiperConfig("node1")
+	cfg.Compression.Selector = "link=radio"
+	cfg.Gossip.RetransmitMult = 10
+	transport := &recordingTransport{Transport: network.NewTransport("node1")}
+	g1 := newTestGossiperWithConfig(t, cfg, transport, NewBinaryCodec(WithCompression(32)))
+
+	var peers []Gossiper
+	for id, link := range map[string]string{"node2": "radio", "node3": "fiber"} {
+		g := newTestGossiperWithConfig(t, testGossiperConfig(id), network.NewTransport(id), NewBinaryCodec(),
+			WithNodeMetadata(types.NodeMetadata{Labels: map[string]string{"link": link}}))
+		if err := g.Join("node1"); err != nil {
+			t.Fatalf("%s failed to join: %v", id, err)
+		}
+		peers = append(peers, g)
+	}
+
+	all := []types.NodeID{"node1", "node2", "node3"}
+	for _, g := range append(peers, g1) {
+		waitFor(t, 3*time.Second, func() bool { return knowsAlive(g, all...) }, "membership to converge")
+	}
+
+	// Metadata-heavy gossip is worth compressing
+	labels := make(map[string]string)
+	for i := 0; i < 20; i++ {
+		labels[fmt.Sprintf("feature-%d", i)] = "enabled"
+	}
+	if err := g1.UpdateMetadata(types.NodeMetadata{Labels: labels}); err != nil {
+		t.Fatalf("UpdateMetadata failed: %v", err)
+	}
+
+	anyCompressed := func(addr string) bool {
+		for _, data := range transport.datagramsTo(addr) {
+			if isCompressed(t, data) {
+				return true
+			}
+		}
+		return false
+	}
+	waitFor(t, 3*time.Second, func() bool { return anyCompressed("node2") }, "a compressed message to node2")
+	if anyCompressed("node3") {
+		t.Error("node1 compressed messages to node3, which does not match the selector")
+	}
+}
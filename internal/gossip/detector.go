@@ -0,0 +1,329 @@
+package gossip
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/michael-martinez-dev/adaptive-hive/internal/config"
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+// FailureDetector runs the SWIM probe loop and answers probes from peers
+type FailureDetector interface {
+	// Run probes one member every ProbeInterval, in shuffled round-robin
+	// order, until ctx is cancelled
+	Run(ctx context.Context)
+
+	// Probe pings node directly, falling back to indirect pings through
+	// other members, and suspects it if neither is acknowledged, or
+	// confirms the suspicion if it already is suspect.
+	// Returns whether the node was reachable
+	Probe(node types.Node) bool
+
+	// Suspicions returns the timers that turn suspect members into dead ones
+	Suspicions() SuspicionManager
+
+	// HandleMessage processes a decoded Ping, PingReq, Ack or Nack received
+	// from addr. Returns false for any other message
+	HandleMessage(msg any, from string) bool
+}
+
+type failureDetector struct {
+	cfg       config.FailureDetectorConfig
+	local     types.NodeID
+	send      func(addr string, msg any) error // errors only if msg could not be sent; a queued msg that is lost later just gets no reply
+	members   Membership
+	selection SelectionStrategy
+	scheduler ProbeScheduler
+	suspicion SuspicionManager
+
+	seqNo atomic.Uint32
+
+	mu      sync.Mutex
+	waiters map[uint32]*ackWaiter
+}
+
+// ackWaiter collects the replies to one outstanding sequence number
+type ackWaiter struct {
+	ackCh  chan struct{}
+	nackCh chan struct{}
+}
+
+func NewFailureDetector(cfg config.FailureDetectorConfig, local types.NodeID, transport Transport, codec Codec, members Membership) FailureDetector {
+	send := func(addr string, msg any) error {
+		data, err := codec.Encode(msg)
+		if err != nil {
+			return err
+		}
+		return transport.SendTo(addr, data)
+	}
+	return newFailureDetector(cfg, local, members, NewUniformSelection(), send)
+}
+
+// newFailureDetector creates a detector that delivers every message through
+// send, letting the caller piggyback gossip on outgoing probes. selection
+// orders probe rounds and picks indirect-ping helpers
+func newFailureDetector(cfg config.FailureDetectorConfig, local types.NodeID, members Membership, selection SelectionStrategy, send func(addr string, msg any) error) *failureDetector {
+	return &failureDetector{
+		cfg:       cfg,
+		local:     local,
+		send:      send,
+		members:   members,
+		selection: selection,
+		scheduler: NewProbeScheduler(members, local, selection),
+		suspicion: NewSuspicionManager(cfg, members),
+		waiters:   make(map[uint32]*ackWaiter),
+	}
+}
+
+func (d *failureDetector) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.ProbeInterval)
+	defer ticker.Stop()
+	defer d.suspicion.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			node, ok := d.scheduler.Next()
+			if !ok {
+				continue
+			}
+			d.Probe(node)
+		}
+	}
+}
+
+func (d *failureDetector) Probe(node types.Node) bool {
+	start := time.Now()
+	seq := d.nextSeqNo()
+	waiter := d.register(seq)
+	defer d.unregister(seq)
+
+	ping := &Ping{
+		MessageHeader: d.header(MessageTypePing, seq),
+		Target:        string(node.ID),
+	}
+	if err := d.send(node.Address, ping); err == nil {
+		if waiter.wait(d.cfg.ProbeTimeout) {
+			return true
+		}
+	}
+
+	helpers := selectPeers(d.members, d.selection, d.cfg.IndirectNodes, d.local, node.ID)
+	if len(helpers) > 0 {
+		req := &PingReq{
+			Ping: Ping{
+				MessageHeader: d.header(MessageTypePingReq, seq),
+				Target:        string(node.ID),
+			},
+			TargetAdder: node.Address,
+		}
+		for _, helper := range helpers {
+			_ = d.send(helper.Address, req)
+		}
+
+		// Indirect probes get whatever is left of the probe interval
+		remaining := d.cfg.ProbeInterval - time.Since(start)
+		if remaining < d.cfg.ProbeTimeout {
+			remaining = d.cfg.ProbeTimeout
+		}
+		if waiter.waitIndirect(remaining, len(helpers)) {
+			return true
+		}
+	}
+
+	if d.members.Suspect(node.ID) {
+		// A refutation may have raised the incarnation during the probe, and
+		// the timer only declares the node dead at the one it suspects
+		if current, exists := d.members.GetNode(node.ID); exists {
+			d.suspicion.Suspect(current.ID, current.Incarnation, d.local)
+		}
+		return false
+	}
+
+	// Already suspect, most likely through gossip, so our failed probe is
+	// an independent confirmation of someone else's suspicion
+	current, exists := d.members.GetNode(node.ID)
+	if exists && current.State == types.StateSuspect && d.suspicion.Suspect(current.ID, current.Incarnation, d.local) {
+		if confirmer, ok := d.members.(suspicionConfirmer); ok {
+			confirmer.ConfirmSuspect(current.ID)
+		}
+	}
+	return false
+}
+
+// suspicionConfirmer is implemented by memberships that tell the rest of
+// the cluster when the local node confirms a suspicion it did not raise
+type suspicionConfirmer interface {
+	ConfirmSuspect(id types.NodeID)
+}
+
+func (d *failureDetector) Suspicions() SuspicionManager {
+	return d.suspicion
+}
+
+func (d *failureDetector) HandleMessage(msg any, from string) bool {
+	switch m := msg.(type) {
+	case *Ping:
+		d.handlePing(m, from)
+	case *PingReq:
+		d.handlePingReq(m, from)
+	case *Ack:
+		d.handleAck(m)
+	case *Nack:
+		d.handleNack(m)
+	default:
+		return false
+	}
+	return true
+}
+
+func (d *failureDetector) handlePing(ping *Ping, from string) {
+	if ping.Target != "" && ping.Target != string(d.local) {
+		return // meant for a node that used to live at this address
+	}
+
+	ack := &Ack{MessageHeader: d.header(MessageTypeAck, ping.SeqNo)}
+	_ = d.send(from, ack)
+}
+
+// handlePingReq pings the target on behalf of the requester and relays
+// the outcome under the requester's sequence number
+func (d *failureDetector) handlePingReq(req *PingReq, from string) {
+	seq := d.nextSeqNo()
+	waiter := d.register(seq)
+
+	ping := &Ping{
+		MessageHeader: d.header(MessageTypePing, seq),
+		Target:        req.Target,
+	}
+	if err := d.send(req.TargetAdder, ping); err != nil {
+		d.unregister(seq)
+		return
+	}
+
+	go func() {
+		defer d.unregister(seq)
+
+		if waiter.wait(d.cfg.ProbeTimeout) {
+			_ = d.send(from, &Ack{MessageHeader: d.header(MessageTypeAck, req.SeqNo)})
+			return
+		}
+		_ = d.send(from, &Nack{MessageHeader: d.header(MessageTypeNack, req.SeqNo)})
+	}()
+}
+
+func (d *failureDetector) handleAck(ack *Ack) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if waiter, ok := d.waiters[ack.SeqNo]; ok {
+		select {
+		case waiter.ackCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (d *failureDetector) handleNack(nack *Nack) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if waiter, ok := d.waiters[nack.SeqNo]; ok {
+		select {
+		case waiter.nackCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// pingAddr pings whichever node listens on addr, without naming a target,
+// and waits up to timeout for the Ack
+func (d *failureDetector) pingAddr(addr string, timeout time.Duration) bool {
+	return d.request(addr, timeout, func(seq uint32) any {
+		return &Ping{MessageHeader: d.header(MessageTypePing, seq)}
+	})
+}
+
+// request sends the message built for a fresh sequence number to addr and
+// waits up to timeout for an Ack carrying that sequence number
+func (d *failureDetector) request(addr string, timeout time.Duration, build func(seq uint32) any) bool {
+	seq := d.nextSeqNo()
+	waiter := d.register(seq)
+	defer d.unregister(seq)
+
+	if err := d.send(addr, build(seq)); err != nil {
+		return false
+	}
+	return waiter.wait(timeout)
+}
+
+func (d *failureDetector) header(msgType MessageType, seq uint32) MessageHeader {
+	return MessageHeader{
+		Type:     msgType,
+		SeqNo:    seq,
+		SourceID: string(d.local),
+	}
+}
+
+func (d *failureDetector) nextSeqNo() uint32 {
+	return d.seqNo.Add(1)
+}
+
+func (d *failureDetector) register(seq uint32) *ackWaiter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	waiter := &ackWaiter{
+		ackCh:  make(chan struct{}, 1),
+		nackCh: make(chan struct{}, d.cfg.IndirectNodes),
+	}
+	d.waiters[seq] = waiter
+	return waiter
+}
+
+func (d *failureDetector) unregister(seq uint32) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.waiters, seq)
+}
+
+// wait blocks until an ack arrives or the timeout passes
+func (w *ackWaiter) wait(timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-w.ackCh:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// waitIndirect blocks until an ack arrives, every helper has sent a nack,
+// or the timeout passes
+func (w *ackWaiter) waitIndirect(timeout time.Duration, helpers int) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	nacks := 0
+	for {
+		select {
+		case <-w.ackCh:
+			return true
+		case <-w.nackCh:
+			nacks++
+			if nacks >= helpers {
+				return false
+			}
+		case <-timer.C:
+			return false
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package gossip
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/michael-martinez-dev/adaptive-hive/internal/config"
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+// --- Helpers --- //
+
+// recordingMembership records Suspect and Dead calls and applies them
+// through Merge at the node's current incarnation
+type recordingMembership struct {
+	Membership
+
+	mu        sync.Mutex
+	suspected []types.NodeID
+	dead      []types.NodeID
+}
+
+func (m *recordingMembership) Suspect(id types.NodeID) bool {
+	m.mu.Lock()
+	m.suspected = append(m.suspected, id)
+	m.mu.Unlock()
+
+	return m.applyState(id, types.StateSuspect)
+}
+
+func (m *recordingMembership) Dead(id types.NodeID) bool {
+	m.mu.Lock()
+	m.dead = append(m.dead, id)
+	m.mu.Unlock()
+
+	return m.applyState(id, types.StateDead)
+}
+
+func (m *recordingMembership) applyState(id types.NodeID, state types.NodeState) bool {
+	node, exists := m.GetNode(id)
+	if !exists {
+		return false
+	}
+	return m.Merge(GossipEntry{NodeID: id, Address: node.Address, State: state, Incarnation: node.Incarnation})
+}
+
+func (m *recordingMembership) wasDeclaredDead(id types.NodeID) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return slices.Contains(m.dead, id)
+}
+
+func (m *recordingMembership) wasSuspected(id types.NodeID) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return slices.Contains(m.suspected, id)
+}
+
+func testDetectorConfig() config.FailureDetectorConfig {
+	return config.FailureDetectorConfig{
+		ProbeInterval: 200 * time.Millisecond,
+		ProbeTimeout:  50 * time.Millisecond,
+		IndirectNodes: 2,
+		SuspicionMult: 4,
+
+		SuspicionMaxTimeoutMult: 3,
+	}
+}
+
+type detectorNode struct {
+	id        types.NodeID
+	transport *TestTransport
+	members   *recordingMembership
+	detector  FailureDetector
+}
+
+// newDetectorCluster starts a detector for each id on network, each of which
+// knows about every other node
+func newDetectorCluster(t *testing.T, network *TestNetwork, ids ...types.NodeID) map[types.NodeID]*detectorNode {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	nodes := make(map[types.NodeID]*detectorNode)
+	for _, id := range ids {
+		transport := network.NewTransport(string(id))
+		if err := transport.Start(ctx); err != nil {
+			t.Fatalf("failed to start %s", id)
+		}
+
+		members := &recordingMembership{Membership: NewMembership()}
+		for _, other := range ids {
+			if other != id {
+				members.Merge(GossipEntry{NodeID: other, Address: string(other), State: types.StateAlive, Incarnation: 1})
+			}
+		}
+
+		detector := NewFailureDetector(testDetectorConfig(), id, transport, NewCodec(), members)
+		go serveDetector(ctx, transport, detector)
+
+		nodes[id] = &detectorNode{id: id, transport: transport, members: members, detector: detector}
+	}
+	return nodes
+}
+
+// serveDetector feeds decoded messages from transport to detector
+func serveDetector(ctx context.Context, transport Transport, detector FailureDetector) {
+	codec := NewCodec()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case raw := <-transport.Messages():
+			msg, err := codec.Decode(raw.Payload)
+			if err != nil {
+				continue
+			}
+			detector.HandleMessage(msg, raw.From)
+		}
+	}
+}
+
+func TestFailureDetector_DirectProbeSucceeds(t *testing.T) {
+	network := NewTestNetwork()
+	nodes := newDetectorCluster(t, network, "node1", "node2")
+
+	target, _ := nodes["node1"].members.GetNode("node2")
+	if !nodes["node1"].detector.Probe(target) {
+		t.Error("direct probe failed")
+	}
+
+	if nodes["node1"].members.wasSuspected("node2") {
+		t.Error("reachable node was suspected")
+	}
+}
+
+func TestFailureDetector_IndirectProbeSucceeds(t *testing.T) {
+	network := NewTestNetwork()
+	nodes := newDetectorCluster(t, network, "node1", "node2", "node3")
+
+	// node1 cannot reach node2, but node3 can
+	network.Partition("node1", "node2")
+
+	target, _ := nodes["node1"].members.GetNode("node2")
+	if !nodes["node1"].detector.Probe(target) {
+		t.Error("indirect probe failed")
+	}
+
+	if nodes["node1"].members.wasSuspected("node2") {
+		t.Error("indirectly reachable node was suspected")
+	}
+}
+
+func TestFailureDetector_UnreachableNodeIsSuspected(t *testing.T) {
+	network := NewTestNetwork()
+	nodes := newDetectorCluster(t, network, "node1", "node2", "node3")
+
+	if err := nodes["node2"].transport.Stop(); err != nil {
+		t.Fatal("could not stop node2")
+	}
+
+	target, _ := nodes["node1"].members.GetNode("node2")
+	if nodes["node1"].detector.Probe(target) {
+		t.Error("probe of stopped node succeeded")
+	}
+
+	if !nodes["node1"].members.wasSuspected("node2") {
+		t.Error("unreachable node was not suspected")
+	}
+}
+
+func TestFailureDetector_SuspectedNodeDeclaredDead(t *testing.T) {
+	network := NewTestNetwork()
+	nodes := newDetectorCluster(t, network, "node1", "node2")
+
+	if err := nodes["node2"].transport.Stop(); err != nil {
+		t.Fatal("could not stop node2")
+	}
+
+	target, _ := nodes["node1"].members.GetNode("node2")
+	nodes["node1"].detector.Probe(target)
+
+	// Two members gives the floor of SuspicionMult * ProbeInterval
+	deadline := time.After(2 * time.Second)
+	for !nodes["node1"].members.wasDeclaredDead("node2") {
+		select {
+		case <-deadline:
+			t.Fatal("suspected node was never declared dead")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestFailureDetector_SuspicionStartsAtCurrentIncarnation(t *testing.T) {
+	network := NewTestNetwork()
+	nodes := newDetectorCluster(t, network, "node1", "node2")
+
+	if err := nodes["node2"].transport.Stop(); err != nil {
+		t.Fatal("could not stop node2")
+	}
+
+	// node2 refutes an old suspicion while it is being probed
+	target, _ := nodes["node1"].members.GetNode("node2")
+	nodes["node1"].members.Merge(GossipEntry{NodeID: "node2", Address: "node2", State: types.StateAlive, Incarnation: 2})
+	nodes["node1"].detector.Probe(target)
+
+	deadline := time.After(2 * time.Second)
+	for !nodes["node1"].members.wasDeclaredDead("node2") {
+		select {
+		case <-deadline:
+			t.Fatal("node suspected at a newer incarnation was never declared dead")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestFailureDetector_NacksEndIndirectProbeEarly(t *testing.T) {
+	network := NewTestNetwork()
+	nodes := newDetectorCluster(t, network, "node1", "node2", "node3")
+
+	// node3 is reachable but cannot reach node2 either, so it answers with a Nack
+	network.Partition("node2", "node1", "node3")
+
+	start := time.Now()
+	target, _ := nodes["node1"].members.GetNode("node2")
+	if nodes["node1"].detector.Probe(target) {
+		t.Error("probe of partitioned node succeeded")
+	}
+
+	// Without the Nack the indirect phase would wait out the probe interval
+	if elapsed := time.Since(start); elapsed >= testDetectorConfig().ProbeInterval {
+		t.Errorf("probe took %s, expected the Nack to end it early", elapsed)
+	}
+
+	if !nodes["node1"].members.wasSuspected("node2") {
+		t.Error("partitioned node was not suspected")
+	}
+}
+
+func TestFailureDetector_RunProbesMembers(t *testing.T) {
+	network := NewTestNetwork()
+	nodes := newDetectorCluster(t, network, "node1", "node2")
+
+	if err := nodes["node2"].transport.Stop(); err != nil {
+		t.Fatal("could not stop node2")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go nodes["node1"].detector.Run(ctx)
+
+	deadline := time.After(2 * time.Second)
+	for !nodes["node1"].members.wasSuspected("node2") {
+		select {
+		case <-deadline:
+			t.Fatal("probe loop never suspected the stopped node")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestFailureDetector_IgnoresMisdirectedPing(t *testing.T) {
+	network := NewTestNetwork()
+	nodes := newDetectorCluster(t, network, "node1")
+
+	observer := network.NewTransport("observer")
+	if err := observer.Start(context.Background()); err != nil {
+		t.Fatal("could not start observer")
+	}
+
+	codec := NewCodec()
+	data, err := codec.Encode(&Ping{
+		MessageHeader: MessageHeader{Type: MessageTypePing, SeqNo: 1, SourceID: "observer"},
+		Target:        "someone-else",
+	})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	if err := observer.SendTo(string(nodes["node1"].id), data); err != nil {
+		t.Fatal("could not send ping")
+	}
+	assertNoMessage(t, observer.Messages(), 100*time.Millisecond)
+}
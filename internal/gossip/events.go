@@ -0,0 +1,167 @@
+package gossip
+
+import (
+	"maps"
+	"sync"
+	"sync/atomic"
+
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+// EventType identifies what happened to a member
+type EventType int
+
+const (
+	EventJoin          EventType = iota // first heard of, or back from dead or left
+	EventUpdate                         // refuted a suspicion, or changed address or metadata
+	EventSuspect                        // became suspect
+	EventDead                           // declared dead
+	EventLeave                          // left gracefully
+	EventReap                           // removed from the membership
+	EventConflict                       // another node claims the same ID from a different address
+	EventClusterMerged                  // merged with the members of another cluster
+)
+
+// String returns a human-readable representation of EventType
+func (t EventType) String() string {
+	switch t {
+	case EventJoin:
+		return "join"
+	case EventUpdate:
+		return "update"
+	case EventSuspect:
+		return "suspect"
+	case EventDead:
+		return "dead"
+	case EventLeave:
+		return "leave"
+	case EventReap:
+		return "reap"
+	case EventConflict:
+		return "conflict"
+	case EventClusterMerged:
+		return "cluster-merged"
+	default:
+		return "unknown"
+	}
+}
+
+// MembershipEvent describes a change to a single member. For conflicts,
+// Before is the member as known and After is the competing claim. Cluster
+// merges describe the other cluster and the combined membership instead
+type MembershipEvent struct {
+	Type   EventType
+	Before types.Node // zero value when the member was not known
+	After  types.Node // zero value for reaps
+
+	Cluster string       // cluster merged with
+	Team    string       // team of the node the merge happened through
+	Members []types.Node // combined membership after the merge
+}
+
+// Subscription delivers membership events to one subscriber. Delivery never
+// blocks the membership: events that do not fit in the buffer are dropped
+type Subscription interface {
+	// Events returns the channel events are delivered on. It is closed by Close
+	Events() <-chan MembershipEvent
+
+	// Dropped returns how many events were dropped because the buffer was full
+	Dropped() uint64
+
+	// Close stops delivery and closes the events channel
+	Close()
+}
+
+type subscription struct {
+	ch      chan MembershipEvent
+	dropped atomic.Uint64
+	hub     *eventHub
+}
+
+func (s *subscription) Events() <-chan MembershipEvent {
+	return s.ch
+}
+
+func (s *subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+func (s *subscription) Close() {
+	s.hub.unsubscribe(s)
+}
+
+// eventHub fans events out to every subscription
+type eventHub struct {
+	mu   sync.RWMutex
+	subs map[*subscription]struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: make(map[*subscription]struct{})}
+}
+
+func (h *eventHub) subscribe(buffer int) Subscription {
+	sub := &subscription{ch: make(chan MembershipEvent, max(buffer, 1)), hub: h}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.subs[sub] = struct{}{}
+	return sub
+}
+
+func (h *eventHub) unsubscribe(sub *subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, exists := h.subs[sub]; exists {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+func (h *eventHub) publish(event MembershipEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subs {
+		select {
+		case sub.ch <- event:
+		default:
+			sub.dropped.Add(1) // subscriber is behind - DROP
+		}
+	}
+}
+
+// classifyChange returns the event for a member going from before to after,
+// or false if nothing a subscriber cares about changed. existed is false
+// when the member was not known before
+func classifyChange(before types.Node, existed bool, after types.Node) (EventType, bool) {
+	if !existed || before.State != after.State {
+		switch after.State {
+		case types.StateSuspect:
+			if !existed {
+				return EventJoin, true
+			}
+			return EventSuspect, true
+		case types.StateDead:
+			return EventDead, true
+		case types.StateLeft:
+			return EventLeave, true
+		}
+		if existed && before.State == types.StateSuspect {
+			return EventUpdate, true // refuted
+		}
+		return EventJoin, true
+	}
+
+	if before.Address != after.Address || !metadataEqual(before.Metadata, after.Metadata) {
+		return EventUpdate, true
+	}
+	return 0, false
+}
+
+func metadataEqual(a, b types.NodeMetadata) bool {
+	return a.Resources == b.Resources && a.Priority == b.Priority && a.Protocol == b.Protocol &&
+		a.Compression == b.Compression && a.Compound == b.Compound && a.Gossip == b.Gossip && maps.Equal(a.Labels, b.Labels)
+}
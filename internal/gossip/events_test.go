@@ -0,0 +1,166 @@
+package gossip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+// --- Helpers --- //
+
+func nextEvent(t *testing.T, sub Subscription) MembershipEvent {
+	t.Helper()
+	select {
+	case event, ok := <-sub.Events():
+		if !ok {
+			t.Fatal("events channel closed")
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for an event")
+		return MembershipEvent{}
+	}
+}
+
+func expectNoEvent(t *testing.T, sub Subscription) {
+	t.Helper()
+	select {
+	case event := <-sub.Events():
+		t.Fatalf("unexpected %s event for %s", event.Type, event.After.ID)
+	default:
+	}
+}
+
+func TestMembershipEvents_Lifecycle(t *testing.T) {
+	membership := NewMembership()
+	sub := membership.Subscribe(16)
+	defer sub.Close()
+
+	_ = membership.Merge(GossipEntry{NodeID: "node1", Address: "a", State: types.StateAlive, Incarnation: 1})
+	event := nextEvent(t, sub)
+	if event.Type != EventJoin || event.Before.ID != "" || event.After.ID != "node1" {
+		t.Errorf("event = %s %q -> %q, want join of node1", event.Type, event.Before.ID, event.After.ID)
+	}
+
+	_ = membership.Suspect("node1")
+	event = nextEvent(t, sub)
+	if event.Type != EventSuspect || event.Before.State != types.StateAlive || event.After.State != types.StateSuspect {
+		t.Errorf("event = %s %s -> %s, want suspect", event.Type, event.Before.State, event.After.State)
+	}
+
+	_ = membership.Merge(GossipEntry{NodeID: "node1", Address: "a", State: types.StateAlive, Incarnation: 2})
+	if event = nextEvent(t, sub); event.Type != EventUpdate {
+		t.Errorf("refutation event = %s, want update", event.Type)
+	}
+
+	_ = membership.Dead("node1")
+	if event = nextEvent(t, sub); event.Type != EventDead {
+		t.Errorf("event = %s, want dead", event.Type)
+	}
+
+	_ = membership.Merge(GossipEntry{NodeID: "node1", Address: "a", State: types.StateAlive, Incarnation: 3})
+	if event = nextEvent(t, sub); event.Type != EventJoin {
+		t.Errorf("rejoin event = %s, want join", event.Type)
+	}
+
+	_ = membership.Merge(GossipEntry{NodeID: "node1", Address: "a", State: types.StateLeft, Incarnation: 4})
+	if event = nextEvent(t, sub); event.Type != EventLeave {
+		t.Errorf("event = %s, want leave", event.Type)
+	}
+
+	membership.Remove("node1")
+	event = nextEvent(t, sub)
+	if event.Type != EventReap || event.Before.ID != "node1" || event.After.ID != "" {
+		t.Errorf("event = %s %q -> %q, want reap of node1", event.Type, event.Before.ID, event.After.ID)
+	}
+}
+
+func TestMembershipEvents_MetadataUpdate(t *testing.T) {
+	membership := NewMembership()
+	_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 1})
+
+	sub := membership.Subscribe(16)
+	defer sub.Close()
+
+	// A higher incarnation that changes nothing else is not an event
+	_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 2})
+	expectNoEvent(t, sub)
+
+	_ = membership.Merge(GossipEntry{
+		NodeID: "node1", State: types.StateAlive, Incarnation: 3,
+		Metadata: &types.NodeMetadata{Labels: map[string]string{"zone": "a"}},
+	})
+	event := nextEvent(t, sub)
+	if event.Type != EventUpdate || event.After.Metadata.Labels["zone"] != "a" || event.Before.Metadata.Labels != nil {
+		t.Errorf("event = %s %+v -> %+v, want metadata update", event.Type, event.Before.Metadata, event.After.Metadata)
+	}
+}
+
+func TestMembershipEvents_LocalNode(t *testing.T) {
+	membership := NewMembership(WithLocalNode(types.Node{ID: "local", Incarnation: 1}))
+	sub := membership.Subscribe(16)
+	defer sub.Close()
+
+	_, _ = membership.UpdateLocalMetadata(types.NodeMetadata{Priority: 1})
+	if event := nextEvent(t, sub); event.Type != EventUpdate {
+		t.Errorf("event = %s, want update", event.Type)
+	}
+
+	_, _ = membership.LeaveLocal()
+	if event := nextEvent(t, sub); event.Type != EventLeave {
+		t.Errorf("event = %s, want leave", event.Type)
+	}
+}
+
+func TestMembershipEvents_FanOutAndClose(t *testing.T) {
+	membership := NewMembership()
+	sub1 := membership.Subscribe(4)
+	sub2 := membership.Subscribe(4)
+	defer sub2.Close()
+
+	_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 1})
+	for _, sub := range []Subscription{sub1, sub2} {
+		if event := nextEvent(t, sub); event.After.ID != "node1" {
+			t.Errorf("event for %q, want node1", event.After.ID)
+		}
+	}
+
+	sub1.Close()
+	sub1.Close()
+	if _, ok := <-sub1.Events(); ok {
+		t.Error("events channel still open after Close")
+	}
+
+	_ = membership.Merge(GossipEntry{NodeID: "node2", State: types.StateAlive, Incarnation: 1})
+	if event := nextEvent(t, sub2); event.After.ID != "node2" {
+		t.Errorf("event for %q, want node2", event.After.ID)
+	}
+}
+
+func TestMembershipEvents_SlowSubscriberDoesNotBlock(t *testing.T) {
+	membership := NewMembership()
+	sub := membership.Subscribe(2)
+	defer sub.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 5 {
+			_ = membership.Merge(GossipEntry{NodeID: types.NodeID(rune('a' + i)), State: types.StateAlive, Incarnation: 1})
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Merge blocked on a full subscriber")
+	}
+
+	if sub.Dropped() != 3 {
+		t.Errorf("Dropped = %d, want 3", sub.Dropped())
+	}
+	if len(sub.Events()) != 2 {
+		t.Errorf("buffered events = %d, want 2", len(sub.Events()))
+	}
+}
@@ -0,0 +1,825 @@
+package gossip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/michael-martinez-dev/adaptive-hive/internal/config"
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+var (
+	ErrGossiperStarted    = errors.New("gossiper is already started")
+	ErrGossiperNotStarted = errors.New("gossiper is not started")
+	ErrNoSeedsResponded   = errors.New("no seeds responded")
+	ErrLeaveTimeout       = errors.New("timed out waiting for members to acknowledge leave")
+	ErrLeft               = errors.New("gossiper has left the cluster")
+	ErrNoAdvertiseAddr    = errors.New("transport address cannot be advertised, set AdvertiseAddr")
+)
+
+// Gossiper ties the transport, codec, membership, failure detector and
+// broadcast queue together into a running SWIM member
+type Gossiper interface {
+	// Start starts the transport, the receive loop and the probe loop
+	Start(ctx context.Context) error
+
+	// Join announces this node to each seed address and waits for them to
+	// answer. With a stream transport it exchanges full state with each
+	// seed, so the node catches up in one round trip. Without seeds, the
+	// peers restored from the last membership snapshot are used instead
+	// Returns an error only if no seed answered
+	Join(seeds ...string) error
+
+	// Leave marks this node as left, stops probing and announces the leave
+	// to every live member. Waits up to timeout for them to acknowledge it
+	Leave(timeout time.Duration) error
+
+	// Shutdown stops every loop and the transport, then saves a final
+	// membership snapshot if a data dir is configured
+	Shutdown() error
+
+	// UpdateMetadata publishes new metadata for this node by bumping its
+	// incarnation and gossiping the change
+	UpdateMetadata(meta types.NodeMetadata) error
+
+	// Members returns the membership this gossiper maintains
+	Members() Membership
+}
+
+type gossiper struct {
+	cfg        config.Config
+	transport  Transport
+	stream     StreamTransport // nil disables push-pull
+	codec      Codec
+	protocol   types.ProtocolRange // versions codec speaks, advertised in our metadata
+	metadata   types.NodeMetadata
+	members    Membership
+	broadcasts BroadcastQueue
+	selection  SelectionStrategy
+	events     *eventHub // shared with members, so cluster merges reach its subscribers
+	detector   *failureDetector
+	rejoin     []string // peer addresses restored from the last snapshot
+	outbox     chan outgoing
+
+	lastChecksumSync atomic.Int64 // unix nanos of the last checksum-triggered push-pull
+
+	compressSelector LabelSelector // from cfg.Compression, nil if unset
+
+	clusterMu       sync.Mutex
+	mergedClusters  map[string]bool // foreign clusters already merged with
+	syncingClusters map[string]bool // foreign clusters with a merge sync in flight
+
+	mu          sync.Mutex
+	running     bool
+	cancel      context.CancelFunc
+	probeCancel context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+// GossiperOption configures optional Gossiper behaviour
+type GossiperOption func(*gossiper)
+
+// WithStreamTransport enables push-pull anti-entropy over stream, both
+// periodically and while joining. Peers are expected to listen for streams
+// on the same address as their datagram transport
+func WithStreamTransport(stream StreamTransport) GossiperOption {
+	return func(g *gossiper) {
+		g.stream = stream
+	}
+}
+
+// WithNodeMetadata sets the metadata this node starts out advertising
+func WithNodeMetadata(meta types.NodeMetadata) GossiperOption {
+	return func(g *gossiper) {
+		g.metadata = meta
+	}
+}
+
+// WithSelectionStrategy overrides the peer selection strategy built from
+// cfg.Selection
+func WithSelectionStrategy(strategy SelectionStrategy) GossiperOption {
+	return func(g *gossiper) {
+		g.selection = strategy
+	}
+}
+
+// NewGossiper creates a Gossiper for the node described by cfg. The node
+// advertises cfg.Node.AdvertiseAddr, or the transport's local address once
+// Start has bound it
+func NewGossiper(cfg config.Config, transport Transport, codec Codec, opts ...GossiperOption) (Gossiper, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	var compressSelector LabelSelector
+	if cfg.Compression.Selector != "" {
+		selector, err := ParseSelector(cfg.Compression.Selector)
+		if err != nil {
+			return nil, err
+		}
+		compressSelector = selector
+	}
+
+	addr := cfg.Node.AdvertiseAddr
+	if addr == "" {
+		addr = transport.LocalAddr()
+	}
+
+	g := &gossiper{
+		cfg:       cfg,
+		transport: transport,
+		codec:     codec,
+		protocol:  codecVersions(codec),
+		selection: newSelectionStrategy(cfg.Selection),
+		events:    newEventHub(),
+		outbox:    make(chan outgoing, outboxSize),
+
+		compressSelector: compressSelector,
+
+		mergedClusters:  make(map[string]bool),
+		syncingClusters: make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	g.advertise(&g.metadata)
+
+	g.broadcasts = NewBroadcastQueue(cfg.Gossip, func() int { return g.members.Len() })
+	g.members = NewMembership(
+		WithLocalNode(types.Node{
+			ID:          types.NodeID(cfg.Node.ID),
+			Address:     addr,
+			State:       types.StateAlive,
+			Incarnation: 1,
+			Cluster:     cfg.Cluster.ID,
+			Metadata:    g.metadata,
+		}),
+		WithRefuteHandler(g.broadcasts.Queue),
+		WithConflictPolicy(cfg.Membership.ConflictPolicy),
+		WithConflictWindow(cfg.Membership.ConflictWindow),
+		withEventHub(g.events),
+	)
+
+	if cfg.Node.DataDir != "" {
+		if err := g.restoreSnapshot(); err != nil {
+			return nil, err
+		}
+	}
+
+	detectorMembers := &gossipingMembership{Membership: g.members, local: types.NodeID(cfg.Node.ID), broadcasts: g.broadcasts}
+	g.detector = newFailureDetector(cfg.FailureDetector, types.NodeID(cfg.Node.ID), detectorMembers, g.selection, g.send)
+
+	return g, nil
+}
+
+func (g *gossiper) Start(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.running {
+		return ErrGossiperStarted
+	}
+
+	if err := g.transport.Start(ctx); err != nil {
+		return err
+	}
+	if err := g.resolveAddress(); err != nil {
+		_ = g.transport.Stop()
+		return err
+	}
+	if g.stream != nil {
+		if err := g.stream.Start(ctx); err != nil {
+			_ = g.transport.Stop()
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	probeCtx, probeCancel := context.WithCancel(ctx)
+	g.cancel = cancel
+	g.probeCancel = probeCancel
+	g.running = true
+
+	g.announce()
+
+	g.wg.Add(4)
+	go func() {
+		defer g.wg.Done()
+		g.receiveLoop(ctx)
+	}()
+	go func() {
+		defer g.wg.Done()
+		g.sendLoop(ctx)
+	}()
+	go func() {
+		defer g.wg.Done()
+		g.detector.Run(probeCtx)
+	}()
+	go func() {
+		defer g.wg.Done()
+		g.reapLoop(ctx)
+	}()
+
+	if g.cfg.Gossip.GossipInterval > 0 {
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			g.gossipLoop(probeCtx)
+		}()
+	}
+
+	if g.cfg.Node.DataDir != "" && g.cfg.Membership.SnapshotInterval > 0 {
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			g.snapshotLoop(ctx)
+		}()
+	}
+
+	if g.stream != nil {
+		g.wg.Add(2)
+		go func() {
+			defer g.wg.Done()
+			g.streamLoop(ctx)
+		}()
+		go func() {
+			defer g.wg.Done()
+			g.pushPullLoop(probeCtx)
+		}()
+	}
+
+	return nil
+}
+
+func (g *gossiper) Join(seeds ...string) error {
+	if !g.isRunning() {
+		return ErrGossiperNotStarted
+	}
+
+	g.announce()
+
+	if len(seeds) == 0 {
+		seeds = g.rejoin
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	joined := 0
+	for _, seed := range seeds {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			if g.joinSeed(addr) {
+				mu.Lock()
+				joined++
+				mu.Unlock()
+			}
+		}(seed)
+	}
+	wg.Wait()
+
+	if joined == 0 && len(seeds) > 0 {
+		return ErrNoSeedsResponded
+	}
+	return nil
+}
+
+func (g *gossiper) Leave(timeout time.Duration) error {
+	g.mu.Lock()
+	if !g.running {
+		g.mu.Unlock()
+		return ErrGossiperNotStarted
+	}
+	g.probeCancel()
+	g.mu.Unlock()
+
+	entry, _ := g.members.LeaveLocal()
+	g.broadcasts.Queue(entry)
+
+	// Tell every live member directly and wait for each of them to ack,
+	// so nobody has to find out through a failed probe
+	peers := g.members.RandomNodes(g.members.Len(), entry.NodeID)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	acked := 0
+	for _, peer := range peers {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			ok := g.detector.request(addr, timeout, func(seq uint32) any {
+				return &Leave{
+					MessageHeader: g.detector.header(MessageTypeLeave, seq),
+					Incarnation:   entry.Incarnation,
+				}
+			})
+			if ok {
+				mu.Lock()
+				acked++
+				mu.Unlock()
+			}
+		}(peer.Address)
+	}
+	wg.Wait()
+
+	if acked < len(peers) {
+		return ErrLeaveTimeout
+	}
+	return nil
+}
+
+func (g *gossiper) Shutdown() error {
+	g.mu.Lock()
+	if !g.running {
+		g.mu.Unlock()
+		return nil
+	}
+	g.running = false
+	g.cancel()
+	g.mu.Unlock()
+
+	err := g.transport.Stop()
+	if g.stream != nil {
+		if streamErr := g.stream.Stop(); err == nil {
+			err = streamErr
+		}
+	}
+	g.wg.Wait()
+
+	if g.cfg.Node.DataDir != "" {
+		if saveErr := saveSnapshot(g.cfg.Node.DataDir, g.members.AllNodes()); err == nil {
+			err = saveErr
+		}
+	}
+	return err
+}
+
+func (g *gossiper) UpdateMetadata(meta types.NodeMetadata) error {
+	g.advertise(&meta)
+	entry, ok := g.members.UpdateLocalMetadata(meta)
+	if !ok {
+		return ErrLeft
+	}
+	g.broadcasts.Queue(entry)
+	return nil
+}
+
+// advertise fills in what this node can speak, overriding whatever meta
+// claims
+func (g *gossiper) advertise(meta *types.NodeMetadata) {
+	meta.Protocol = g.protocol
+	_, meta.Compression = g.codec.(compressingCodec)
+	meta.Compound = true
+	meta.Gossip = true
+}
+
+func (g *gossiper) Members() Membership {
+	return g.members
+}
+
+func (g *gossiper) isRunning() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.running
+}
+
+// joinSeed syncs full state with the seed when a stream transport is
+// available, falling back to a plain join ping
+func (g *gossiper) joinSeed(addr string) bool {
+	if g.stream != nil && g.pushPull(addr) == nil {
+		return true
+	}
+	return g.detector.pingAddr(addr, g.cfg.FailureDetector.ProbeInterval)
+}
+
+// announce queues the local node's alive entry so it rides on the next
+// outgoing messages
+func (g *gossiper) announce() {
+	if local, ok := g.members.LocalNode(); ok {
+		g.broadcasts.Queue(fullEntryFromNode(local))
+	}
+}
+
+func (g *gossiper) receiveLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case raw, ok := <-g.transport.Messages():
+			if !ok {
+				return
+			}
+			msgs, err := DecodeMessages(g.codec, raw.Payload)
+			if errors.Is(err, ErrUnknownMessageType) {
+				continue // from a newer peer, nothing for us to do - SKIP
+			}
+			if err != nil {
+				continue // corrupt or foreign datagram - DROP
+			}
+			for _, msg := range msgs {
+				g.handle(msg, raw.From)
+			}
+		}
+	}
+}
+
+// resolveAddress advertises the transport's local address now that it is
+// bound, unless cfg.Node.AdvertiseAddr pins one. A wildcard host would send
+// peers to themselves, so it is replaced by the address of a local network
+// interface. Port zero cannot be advertised at all
+func (g *gossiper) resolveAddress() error {
+	if g.cfg.Node.AdvertiseAddr != "" {
+		return nil
+	}
+
+	addr, ok := advertisable(g.transport.LocalAddr())
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoAdvertiseAddr, g.transport.LocalAddr())
+	}
+	if local, _ := g.members.LocalNode(); local.Address != addr {
+		g.members.UpdateLocalAddress(addr)
+	}
+	return nil
+}
+
+// advertisable returns the address peers can reach addr at, filling in a
+// wildcard host from the local interfaces. Anything that is not a
+// host:port, such as a TestNetwork address, is taken as it is
+func advertisable(addr string) (string, bool) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, true
+	}
+	if port == "0" {
+		return "", false
+	}
+
+	ip := net.ParseIP(host)
+	if host != "" && (ip == nil || !ip.IsUnspecified()) {
+		return addr, true
+	}
+	if ip, ok := interfaceIP(ip == nil || ip.To4() == nil); ok {
+		return net.JoinHostPort(ip.String(), port), true
+	}
+	return "", false
+}
+
+// interfaceIP returns the first global unicast address of an interface that
+// is up, IPv4 ones first. IPv6 addresses are only considered if allowIPv6
+func interfaceIP(allowIPv6 bool) (net.IP, bool) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, false
+	}
+
+	var fallback net.IP
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			ipNet, ok := a.(*net.IPNet)
+			if !ok || !ipNet.IP.IsGlobalUnicast() {
+				continue
+			}
+			if ipNet.IP.To4() != nil {
+				return ipNet.IP, true
+			}
+			if allowIPv6 && fallback == nil {
+				fallback = ipNet.IP
+			}
+		}
+	}
+	return fallback, fallback != nil
+}
+
+// reapLoop periodically purges tombstones that outlived their retention
+func (g *gossiper) reapLoop(ctx context.Context) {
+	ticker := time.NewTicker(g.cfg.Membership.ReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.members.Reap(g.cfg.Membership.TombstoneRetention)
+		}
+	}
+}
+
+// gossipLoop sends queued entries to peers every GossipInterval until ctx
+// is done
+func (g *gossiper) gossipLoop(ctx context.Context) {
+	ticker := time.NewTicker(g.cfg.Gossip.GossipInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.gossip()
+		}
+	}
+}
+
+// gossip sends queued entries to GossipNodes peers picked by the selection
+// strategy, which so decides how much gossip each peer gets
+func (g *gossiper) gossip() {
+	if g.broadcasts.Len() == 0 {
+		return
+	}
+	for _, peer := range selectPeers(g.members, g.selection, g.cfg.Gossip.GossipNodes, types.NodeID(g.cfg.Node.ID)) {
+		if g.fansOutTo(peer, true) {
+			_ = g.send(peer.Address, &Gossip{MessageHeader: g.detector.header(MessageTypeGossip, 0)})
+		}
+	}
+}
+
+// handle merges any piggybacked gossip and then hands msg to the detector.
+// Messages from clusters the merge policy refuses are dropped
+func (g *gossiper) handle(msg any, from string) {
+	if m, ok := msg.(headed); ok {
+		if !g.admits(m.messageHeader()) {
+			return
+		}
+		// Once its gossip is merged, so a merge event lists what came with it
+		defer g.meetCluster(m.messageHeader(), from)
+	}
+
+	switch m := msg.(type) {
+	case *Ping:
+		g.mergeGossip(m.Gossip)
+		if m.Target == "" {
+			// A ping without a target is a join, so make sure the
+			// joiner learns about us. The Ack only carries gossip
+			// to joiners the fan-out leaves out, otherwise it goes
+			// ahead of the Ack
+			g.announce()
+			if peer, known := g.members.GetNodeByAddress(from); g.fansOutTo(peer, known) {
+				_ = g.send(from, &Gossip{MessageHeader: g.detector.header(MessageTypeGossip, 0)})
+			}
+		}
+		g.checkDivergence(m.Checksum, from)
+	case *PingReq:
+		g.mergeGossip(m.Gossip)
+	case *Ack:
+		g.mergeGossip(m.Gossip)
+		g.checkDivergence(m.Checksum, from)
+	case *Gossip:
+		g.mergeGossip(m.Gossip)
+		return
+	case *Leave:
+		g.handleLeave(m, from)
+		return
+	}
+
+	g.detector.HandleMessage(msg, from)
+}
+
+// checkDivergence starts a push-pull with addr if it reported a membership
+// checksum different from ours, at most once per ChecksumSyncInterval.
+// Gossip still in flight makes short-lived mismatches common, so the rate
+// limit keeps them from turning into a sync storm. Without a stream
+// transport, addr is only pushed our state over the packet transport
+func (g *gossiper) checkDivergence(checksum uint64, addr string) {
+	interval := g.cfg.Gossip.ChecksumSyncInterval
+	if interval <= 0 || checksum == 0 || checksum == g.members.Checksum() {
+		return
+	}
+
+	now := time.Now().UnixNano()
+	last := g.lastChecksumSync.Load()
+	if now-last < int64(interval) || !g.lastChecksumSync.CompareAndSwap(last, now) {
+		return
+	}
+
+	if g.stream == nil {
+		_ = g.pushState(addr)
+		return
+	}
+	g.goFromReceiveLoop(func() { _ = g.pushPull(addr) })
+}
+
+// goFromReceiveLoop runs fn in a goroutine that Shutdown waits for. Only
+// call it from the receive loop: that holds its own place in wg, so adding
+// to it here cannot race with Shutdown's Wait
+func (g *gossiper) goFromReceiveLoop(fn func()) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		fn()
+	}()
+}
+
+// handleLeave marks the sender as left, which skips suspicion entirely,
+// and acknowledges the announcement
+func (g *gossiper) handleLeave(leave *Leave, from string) {
+	id := types.NodeID(leave.SourceID)
+
+	addr := from
+	if node, exists := g.members.GetNode(id); exists {
+		addr = node.Address
+	}
+
+	g.mergeGossip([]GossipEntry{{
+		NodeID:      id,
+		Address:     addr,
+		State:       types.StateLeft,
+		Incarnation: leave.Incarnation,
+		Timestamp:   time.Now().UnixNano(),
+	}})
+
+	_ = g.send(from, &Ack{MessageHeader: g.detector.header(MessageTypeAck, leave.SeqNo)})
+}
+
+// mergeGossip applies entries received from a peer, re-gossiping the ones
+// that changed our view and feeding suspicions to the detector. Members of
+// clusters the merge policy refuses are left out, even when a peer of our
+// own cluster passes them along. A suspicion counts as confirmed by the
+// node that raised it, not by the peer passing it along, so a rumor echoed
+// by many peers is still one confirmation
+func (g *gossiper) mergeGossip(entries []GossipEntry) {
+	suspicions := g.detector.Suspicions()
+
+	for _, entry := range entries {
+		if !g.admitsCluster(entry.ClusterID) {
+			continue
+		}
+		merged := g.members.Merge(entry)
+		if merged {
+			g.broadcasts.Queue(entry)
+		}
+
+		node, exists := g.members.GetNode(entry.NodeID)
+		if !exists {
+			continue
+		}
+
+		switch {
+		case node.State == types.StateSuspect && node.Incarnation == entry.Incarnation && entry.State == types.StateSuspect:
+			// Merge has nothing to apply for another originator of the
+			// same suspicion, but the others still need to hear it
+			if suspicions.Suspect(node.ID, node.Incarnation, entry.Suspector) && !merged {
+				g.broadcasts.Queue(entry)
+			}
+		case node.State != types.StateSuspect:
+			suspicions.Refute(node.ID)
+		}
+	}
+}
+
+// send fills msg with queued broadcasts if it carries gossip, encodes it and
+// queues it for sendLoop, or hands it straight to the transport for peers
+// that do not say they unpack compound messages. A queued message is only
+// sent later, so its transport errors are not returned
+func (g *gossiper) send(addr string, msg any) error {
+	maxSize := g.cfg.Gossip.MaxPacketSize
+	peer, known := g.members.GetNodeByAddress(addr)
+	version := g.versionWith(peer)
+	codec := g.codecWith(peer, known)
+
+	msg = withChecksum(msg, g.members.Checksum())
+	g.stamp(msg)
+	setVersion(msg, version)
+
+	if g.carriesGossip(msg, peer, known) {
+		build := func(entries []GossipEntry) any {
+			withEntries, _ := withGossip(msg, entries)
+			return withEntries
+		}
+		entries := g.broadcasts.GetBroadcasts(fitsPacket(codec, maxSize, build))
+		if _, ok := msg.(*Gossip); ok && len(entries) == 0 {
+			return nil // nothing left to say
+		}
+		msg = build(entries)
+	}
+
+	data, err := codec.Encode(msg)
+	if err != nil {
+		return err
+	}
+	if !peer.Metadata.Compound {
+		return g.transport.SendTo(addr, data)
+	}
+
+	select {
+	case g.outbox <- outgoing{addr: addr, version: version, data: data}:
+		return nil
+	default:
+		return g.transport.SendTo(addr, data) // sendLoop is behind, do not wait for it
+	}
+}
+
+// carriesGossip reports whether msg is sent with queued broadcasts. Probe
+// messages carry them to every peer the gossip fan-out leaves out
+func (g *gossiper) carriesGossip(msg any, peer types.Node, known bool) bool {
+	switch msg.(type) {
+	case *Gossip:
+		return true
+	case *Ping, *PingReq, *Ack:
+		return !g.fansOutTo(peer, known)
+	default:
+		return false
+	}
+}
+
+// fansOutTo reports whether peer gets queued broadcasts in Gossip messages.
+// Peers we do not know, such as a node joining through us, and peers that
+// do not say they decode Gossip messages get them on probes instead, as
+// does everyone without a fan-out
+func (g *gossiper) fansOutTo(peer types.Node, known bool) bool {
+	return g.cfg.Gossip.GossipInterval > 0 && known && peer.Metadata.Gossip
+}
+
+// withGossip returns a copy of msg carrying entries, if msg is a message
+// type that can carry gossip
+func withGossip(msg any, entries []GossipEntry) (any, bool) {
+	switch m := msg.(type) {
+	case *Gossip:
+		c := *m
+		c.Gossip = entries
+		return &c, true
+	case *Ping:
+		c := *m
+		c.Gossip = entries
+		return &c, true
+	case *PingReq:
+		c := *m
+		c.Gossip = entries
+		return &c, true
+	case *Ack:
+		c := *m
+		c.Gossip = entries
+		return &c, true
+	default:
+		return msg, false
+	}
+}
+
+// withChecksum returns a copy of msg carrying checksum, if msg is a Ping
+// or an Ack
+func withChecksum(msg any, checksum uint64) any {
+	switch m := msg.(type) {
+	case *Ping:
+		c := *m
+		c.Checksum = checksum
+		return &c
+	case *Ack:
+		c := *m
+		c.Checksum = checksum
+		return &c
+	default:
+		return msg
+	}
+}
+
+// gossipingMembership queues a broadcast whenever the failure detector
+// changes a member's state, so the rest of the cluster hears about it
+type gossipingMembership struct {
+	Membership
+	local      types.NodeID
+	broadcasts BroadcastQueue
+}
+
+func (m *gossipingMembership) Suspect(id types.NodeID) bool {
+	if !m.Membership.Suspect(id) {
+		return false
+	}
+	m.ConfirmSuspect(id)
+	return true
+}
+
+// ConfirmSuspect queues a suspect entry for id raised by the local node
+func (m *gossipingMembership) ConfirmSuspect(id types.NodeID) {
+	if node, exists := m.GetNode(id); exists {
+		entry := entryFromNode(node)
+		entry.Suspector = m.local
+		m.broadcasts.Queue(entry)
+	}
+}
+
+func (m *gossipingMembership) Dead(id types.NodeID) bool {
+	if !m.Membership.Dead(id) {
+		return false
+	}
+	m.queue(id)
+	return true
+}
+
+func (m *gossipingMembership) queue(id types.NodeID) {
+	if node, exists := m.GetNode(id); exists {
+		m.broadcasts.Queue(entryFromNode(node))
+	}
+}
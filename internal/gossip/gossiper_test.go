@@ -0,0 +1,741 @@
+package gossip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/michael-martinez-dev/adaptive-hive/internal/config"
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+// --- Helpers --- //
+
+func testGossiperConfig(id string) config.Config {
+	cfg := config.DefaultConfig()
+	cfg.Node.ID = id
+	cfg.FailureDetector.ProbeInterval = 50 * time.Millisecond
+	cfg.FailureDetector.ProbeTimeout = 20 * time.Millisecond
+	return cfg
+}
+
+func newTestGossiper(t *testing.T, network *TestNetwork, id string) Gossiper {
+	t.Helper()
+	return newTestGossiperWithConfig(t, testGossiperConfig(id), network.NewTransport(id), NewCodec())
+}
+
+// newTestGossiperWithConfig creates and starts a gossiper, shutting it down
+// when the test ends
+func newTestGossiperWithConfig(t *testing.T, cfg config.Config, transport Transport, codec Codec, opts ...GossiperOption) *gossiper {
+	t.Helper()
+	g, err := NewGossiper(cfg, transport, codec, opts...)
+	if err != nil {
+		t.Fatalf("failed to create gossiper %s: %v", cfg.Node.ID, err)
+	}
+
+	if err := g.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start gossiper %s: %v", cfg.Node.ID, err)
+	}
+	t.Cleanup(func() { _ = g.Shutdown() })
+	return g.(*gossiper)
+}
+
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool, what string) {
+	t.Helper()
+	deadline := time.After(timeout)
+	for !cond() {
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for %s", what)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func knowsAlive(g Gossiper, ids ...types.NodeID) bool {
+	for _, id := range ids {
+		node, exists := g.Members().GetNode(id)
+		if !exists || node.State != types.StateAlive {
+			return false
+		}
+	}
+	return true
+}
+
+// sameChecksum reports whether every gossiper has the same view of the live
+// members
+func sameChecksum(gossipers ...Gossiper) bool {
+	for _, g := range gossipers[1:] {
+		if g.Members().Checksum() != gossipers[0].Members().Checksum() {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGossiper_JoinConverges(t *testing.T) {
+	network := NewTestNetwork()
+	g1 := newTestGossiper(t, network, "node1")
+	g2 := newTestGossiper(t, network, "node2")
+	g3 := newTestGossiper(t, network, "node3")
+
+	if err := g2.Join("node1"); err != nil {
+		t.Fatalf("node2 failed to join: %v", err)
+	}
+	if err := g3.Join("node1"); err != nil {
+		t.Fatalf("node3 failed to join: %v", err)
+	}
+
+	all := []types.NodeID{"node1", "node2", "node3"}
+	for _, g := range []Gossiper{g1, g2, g3} {
+		waitFor(t, 3*time.Second, func() bool { return knowsAlive(g, all...) }, "membership to converge")
+	}
+	waitFor(t, 3*time.Second, func() bool { return sameChecksum(g1, g2, g3) }, "checksums to match")
+}
+
+func TestGossiper_BinaryCodecConverges(t *testing.T) {
+	network := NewTestNetwork()
+	var gossipers []Gossiper
+	for _, id := range []string{"node1", "node2", "node3"} {
+		gossipers = append(gossipers, newTestGossiperWithConfig(t, testGossiperConfig(id), network.NewTransport(id), NewBinaryCodec(),
+			WithNodeMetadata(types.NodeMetadata{Labels: map[string]string{"zone": id}})))
+	}
+
+	for _, g := range gossipers[1:] {
+		if err := g.Join("node1"); err != nil {
+			t.Fatalf("failed to join: %v", err)
+		}
+	}
+
+	all := []types.NodeID{"node1", "node2", "node3"}
+	for _, g := range gossipers {
+		waitFor(t, 3*time.Second, func() bool { return knowsAlive(g, all...) }, "membership to converge")
+	}
+	node3, _ := gossipers[0].Members().GetNode("node3")
+	if zone := node3.Metadata.Labels["zone"]; zone != "node3" {
+		t.Errorf("node1 sees node3 in zone %q, want node3", zone)
+	}
+}
+
+func TestGossiper_JoinsOverUDP(t *testing.T) {
+	// Port 0 only becomes a real address once the transport is bound
+	start := func(id string) Gossiper {
+		cfg := testGossiperConfig(id)
+		cfg.Node.BindAddr = "127.0.0.1"
+		cfg.Node.BindPort = "0"
+
+		return newTestGossiperWithConfig(t, cfg, NewUDPTransport(cfg.Node, cfg.Gossip), NewCodec())
+	}
+	g1, g2 := start("node1"), start("node2")
+
+	local, _ := g1.Members().LocalNode()
+	if strings.HasSuffix(local.Address, ":0") {
+		t.Fatalf("node1 advertises %s", local.Address)
+	}
+	if err := g2.Join(local.Address); err != nil {
+		t.Fatalf("node2 failed to join: %v", err)
+	}
+
+	for _, g := range []Gossiper{g1, g2} {
+		waitFor(t, 3*time.Second, func() bool { return knowsAlive(g, "node1", "node2") }, "membership to converge")
+	}
+}
+
+func TestGossiper_AdvertisesInterfaceForWildcardAddress(t *testing.T) {
+	ip, ok := interfaceIP(false)
+	if !ok {
+		t.Skip("no network interface with a global IPv4 address")
+	}
+
+	tests := map[string]struct {
+		bindAddr, advertise string
+		wantHost            string
+	}{
+		"default config":  {config.DefaultConfig().Node.BindAddr, "", ip.String()},
+		"ipv6 wildcard":   {"::", "", ip.String()},
+		"advertised addr": {"0.0.0.0", "127.0.0.1:7946", "127.0.0.1"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := testGossiperConfig("node1")
+			cfg.Node.BindAddr = tt.bindAddr
+			cfg.Node.BindPort = "0"
+			cfg.Node.AdvertiseAddr = tt.advertise
+			g := newTestGossiperWithConfig(t, cfg, NewUDPTransport(cfg.Node, cfg.Gossip), NewCodec())
+
+			local, _ := g.Members().LocalNode()
+			if host, _, _ := net.SplitHostPort(local.Address); host != tt.wantHost {
+				t.Errorf("advertised %s, want host %s", local.Address, tt.wantHost)
+			}
+		})
+	}
+}
+
+func TestGossiper_RefusesPortZero(t *testing.T) {
+	if _, ok := advertisable("127.0.0.1:0"); ok {
+		t.Error("port 0 is advertisable")
+	}
+}
+
+func TestGossiper_JoinWithoutReachableSeedsFails(t *testing.T) {
+	network := NewTestNetwork()
+	g1 := newTestGossiper(t, network, "node1")
+
+	if err := g1.Join("nowhere"); err != ErrNoSeedsResponded {
+		t.Errorf("err = %v, want ErrNoSeedsResponded", err)
+	}
+}
+
+func TestGossiper_RefutesSuspicionOfItself(t *testing.T) {
+	network := NewTestNetwork()
+	g1 := newTestGossiper(t, network, "node1")
+	g2 := newTestGossiper(t, network, "node2")
+
+	if err := g2.Join("node1"); err != nil {
+		t.Fatalf("node2 failed to join: %v", err)
+	}
+	waitFor(t, 3*time.Second, func() bool { return knowsAlive(g1, "node2") }, "node1 to learn node2")
+
+	// A stale rumor reaches node2 claiming it is suspect
+	rumor := network.NewTransport("rumor")
+	if err := rumor.Start(context.Background()); err != nil {
+		t.Fatal("could not start rumor transport")
+	}
+	data, err := NewCodec().Encode(&Ping{
+		MessageHeader: MessageHeader{Type: MessageTypePing, SeqNo: 1, SourceID: "rumor"},
+		Target:        "node2",
+		Gossip:        []GossipEntry{{NodeID: "node2", Address: "node2", State: types.StateSuspect, Incarnation: 1}},
+	})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if err := rumor.SendTo("node2", data); err != nil {
+		t.Fatal("could not send rumor")
+	}
+
+	waitFor(t, 3*time.Second, func() bool {
+		node, exists := g1.Members().GetNode("node2")
+		return exists && node.State == types.StateAlive && node.Incarnation > 1
+	}, "node1 to hear node2's refutation")
+}
+
+func TestGossiper_EchoedSuspicionConfirmsOnce(t *testing.T) {
+	g, err := NewGossiper(testGossiperConfig("node1"), NewTestNetwork().NewTransport("node1"), NewCodec())
+	if err != nil {
+		t.Fatalf("NewGossiper failed: %v", err)
+	}
+	gg := g.(*gossiper)
+	suspicions := gg.detector.Suspicions().(*suspicionManager)
+	t.Cleanup(suspicions.Stop)
+
+	for i := 2; i <= 6; i++ {
+		gg.mergeGossip([]GossipEntry{{NodeID: types.NodeID(fmt.Sprintf("node%d", i)), State: types.StateAlive, Incarnation: 1}})
+	}
+	confirmers := func() int {
+		suspicions.mu.Lock()
+		defer suspicions.mu.Unlock()
+		return len(suspicions.suspicions["node2"].confirmers)
+	}
+
+	relay := func(from string, suspector types.NodeID) {
+		gg.handle(&Ack{
+			MessageHeader: MessageHeader{Type: MessageTypeAck, SourceID: from},
+			Gossip:        []GossipEntry{{NodeID: "node2", State: types.StateSuspect, Incarnation: 1, Suspector: suspector}},
+		}, from)
+	}
+
+	// node3's suspicion reaches us through several peers
+	for _, from := range []string{"node3", "node4", "node5", "node6"} {
+		relay(from, "node3")
+	}
+	if got := confirmers(); got != 1 {
+		t.Errorf("echoed suspicion counted %d times, want once", got)
+	}
+
+	relay("node5", "node4")
+	if got := confirmers(); got != 2 {
+		t.Errorf("confirmers = %d after a second originator, want 2", got)
+	}
+	if got := queuedSuspector(gg, "node2"); got != "node4" {
+		t.Errorf("queued suspector = %q, want the second originator relayed", got)
+	}
+}
+
+func TestGossiper_FailedProbeConfirmsSuspicion(t *testing.T) {
+	g, err := NewGossiper(testGossiperConfig("node1"), NewTestNetwork().NewTransport("node1"), NewCodec())
+	if err != nil {
+		t.Fatalf("NewGossiper failed: %v", err)
+	}
+	gg := g.(*gossiper)
+	suspicions := gg.detector.Suspicions().(*suspicionManager)
+	t.Cleanup(suspicions.Stop)
+
+	for i := 2; i <= 6; i++ {
+		id := fmt.Sprintf("node%d", i)
+		gg.mergeGossip([]GossipEntry{{NodeID: types.NodeID(id), Address: id, State: types.StateAlive, Incarnation: 1}})
+	}
+
+	// node3 suspects node2 first, then our own probe of it fails too
+	gg.mergeGossip([]GossipEntry{{NodeID: "node2", Address: "node2", State: types.StateSuspect, Incarnation: 1, Suspector: "node3"}})
+	node, _ := gg.members.GetNode("node2")
+	if gg.detector.Probe(node) {
+		t.Fatal("Probe of an unreachable node succeeded")
+	}
+
+	suspicions.mu.Lock()
+	confirmers := suspicions.suspicions["node2"].confirmers
+	suspicions.mu.Unlock()
+	if !confirmers["node1"] || len(confirmers) != 2 {
+		t.Errorf("confirmers = %v, want node3 and our own probe", confirmers)
+	}
+	if got := queuedSuspector(gg, "node2"); got != "node1" {
+		t.Errorf("queued suspector = %q, want our confirmation gossiped", got)
+	}
+}
+
+// queuedSuspector returns the Suspector of the entry queued for id
+func queuedSuspector(g *gossiper, id types.NodeID) types.NodeID {
+	for _, entry := range g.broadcasts.GetBroadcasts(func([]GossipEntry) bool { return true }) {
+		if entry.NodeID == id {
+			return entry.Suspector
+		}
+	}
+	return ""
+}
+
+func TestGossiper_Lifecycle(t *testing.T) {
+	network := NewTestNetwork()
+	g, err := NewGossiper(testGossiperConfig("node1"), network.NewTransport("node1"), NewCodec())
+	if err != nil {
+		t.Fatalf("failed to create gossiper: %v", err)
+	}
+
+	if err := g.Join("node2"); err != ErrGossiperNotStarted {
+		t.Errorf("Join before Start: err = %v, want ErrGossiperNotStarted", err)
+	}
+
+	if err := g.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	if err := g.Start(context.Background()); err != ErrGossiperStarted {
+		t.Errorf("second Start: err = %v, want ErrGossiperStarted", err)
+	}
+
+	if err := g.Leave(100 * time.Millisecond); err != nil {
+		t.Errorf("Leave failed: %v", err)
+	}
+
+	if err := g.Shutdown(); err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+	if err := g.Shutdown(); err != nil {
+		t.Errorf("second Shutdown failed: %v", err)
+	}
+}
+
+func TestGossiper_InvalidConfig(t *testing.T) {
+	network := NewTestNetwork()
+
+	if _, err := NewGossiper(config.DefaultConfig(), network.NewTransport("node1"), NewCodec()); err == nil {
+		t.Error("NewGossiper accepted a config without a node id")
+	}
+}
+
+func TestGossiper_LeaveMarksNodeLeft(t *testing.T) {
+	network := NewTestNetwork()
+	g1 := newTestGossiper(t, network, "node1")
+	g2 := newTestGossiper(t, network, "node2")
+	g3 := newTestGossiper(t, network, "node3")
+
+	for _, g := range []Gossiper{g2, g3} {
+		if err := g.Join("node1"); err != nil {
+			t.Fatalf("failed to join: %v", err)
+		}
+	}
+	all := []types.NodeID{"node1", "node2", "node3"}
+	for _, g := range []Gossiper{g1, g2, g3} {
+		waitFor(t, 3*time.Second, func() bool { return knowsAlive(g, all...) }, "membership to converge")
+	}
+
+	if err := g3.Leave(time.Second); err != nil {
+		t.Fatalf("Leave failed: %v", err)
+	}
+	if err := g3.Shutdown(); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	for _, g := range []Gossiper{g1, g2} {
+		node, exists := g.Members().GetNode("node3")
+		if !exists || node.State != types.StateLeft {
+			t.Fatalf("node3 = %v, want left", node.State)
+		}
+	}
+
+	// Left nodes are never probed, so they are never suspected
+	time.Sleep(10 * testGossiperConfig("node1").FailureDetector.ProbeInterval)
+	for _, g := range []Gossiper{g1, g2} {
+		if node, _ := g.Members().GetNode("node3"); node.State != types.StateLeft {
+			t.Errorf("node3 = %s after leaving, want left", node.State)
+		}
+	}
+}
+
+func TestGossiper_LeftNodeDoesNotRefute(t *testing.T) {
+	network := NewTestNetwork()
+	g1 := newTestGossiper(t, network, "node1")
+
+	if err := g1.Leave(100 * time.Millisecond); err != nil {
+		t.Fatalf("Leave failed: %v", err)
+	}
+
+	_ = g1.Members().Merge(GossipEntry{NodeID: "node1", State: types.StateDead, Incarnation: 10})
+
+	local, _ := g1.Members().LocalNode()
+	if local.State != types.StateLeft {
+		t.Errorf("state = %s, want left", local.State)
+	}
+}
+
+func TestGossiper_DetectsFailedNode(t *testing.T) {
+	network := NewTestNetwork()
+	g1 := newTestGossiper(t, network, "node1")
+	g2 := newTestGossiper(t, network, "node2")
+	g3 := newTestGossiper(t, network, "node3")
+
+	for _, g := range []Gossiper{g2, g3} {
+		if err := g.Join("node1"); err != nil {
+			t.Fatalf("failed to join: %v", err)
+		}
+	}
+	all := []types.NodeID{"node1", "node2", "node3"}
+	for _, g := range []Gossiper{g1, g2, g3} {
+		waitFor(t, 3*time.Second, func() bool { return knowsAlive(g, all...) }, "membership to converge")
+	}
+
+	// node3 crashes without leaving
+	if err := g3.Shutdown(); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	for _, g := range []Gossiper{g1, g2} {
+		waitFor(t, 5*time.Second, func() bool {
+			return len(g.Members().GetNodesByState(types.StateDead)) == 1
+		}, "node3 to be declared dead")
+
+		if node, _ := g.Members().GetNode("node3"); node.State != types.StateDead {
+			t.Errorf("node3 = %s, want dead", node.State)
+		}
+	}
+}
+
+func TestGossiper_PropagatesMetadata(t *testing.T) {
+	network := NewTestNetwork()
+	cfg := testGossiperConfig("node1")
+	g1 := newTestGossiperWithConfig(t, cfg, network.NewTransport("node1"), NewCodec(),
+		WithNodeMetadata(types.NodeMetadata{Labels: map[string]string{"zone": "a"}}))
+	g2 := newTestGossiper(t, network, "node2")
+
+	if err := g2.Join("node1"); err != nil {
+		t.Fatalf("node2 failed to join: %v", err)
+	}
+	zoneOf := func(id types.NodeID) string {
+		node, _ := g2.Members().GetNode(id)
+		return node.Metadata.Labels["zone"]
+	}
+	waitFor(t, 3*time.Second, func() bool { return zoneOf("node1") == "a" }, "node2 to learn node1's metadata")
+
+	if err := g1.UpdateMetadata(types.NodeMetadata{Labels: map[string]string{"zone": "b"}}); err != nil {
+		t.Fatalf("UpdateMetadata failed: %v", err)
+	}
+	waitFor(t, 3*time.Second, func() bool { return zoneOf("node1") == "b" }, "node2 to learn node1's new metadata")
+
+	if err := g1.Leave(100 * time.Millisecond); err != nil {
+		t.Fatalf("Leave failed: %v", err)
+	}
+	if err := g1.UpdateMetadata(types.NodeMetadata{}); err != ErrLeft {
+		t.Errorf("UpdateMetadata after Leave: err = %v, want ErrLeft", err)
+	}
+}
+
+func TestGossiper_ReapsLeftNode(t *testing.T) {
+	network := NewTestNetwork()
+	cfg := testGossiperConfig("node1")
+	cfg.Membership.TombstoneRetention = 100 * time.Millisecond
+	cfg.Membership.ReapInterval = 20 * time.Millisecond
+	g1 := newTestGossiperWithConfig(t, cfg, network.NewTransport(cfg.Node.ID), NewCodec())
+	g2 := newTestGossiper(t, network, "node2")
+
+	if err := g2.Join("node1"); err != nil {
+		t.Fatalf("node2 failed to join: %v", err)
+	}
+	waitFor(t, 3*time.Second, func() bool { return knowsAlive(g1, "node2") }, "node1 to learn node2")
+
+	if err := g2.Leave(time.Second); err != nil {
+		t.Fatalf("Leave failed: %v", err)
+	}
+
+	waitFor(t, 3*time.Second, func() bool {
+		_, exists := g1.Members().GetNode("node2")
+		return !exists
+	}, "node1 to reap node2")
+}
+
+func TestGossiper_NodeMovesToNewAddress(t *testing.T) {
+	network := NewTestNetwork()
+	g1 := newTestGossiper(t, network, "node1")
+
+	cfg := testGossiperConfig("node2")
+	before, err := NewGossiper(cfg, network.NewTransport("10.0.0.2"), NewCodec())
+	if err != nil {
+		t.Fatalf("failed to create gossiper: %v", err)
+	}
+	if err := before.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start gossiper: %v", err)
+	}
+	if err := before.Join("node1"); err != nil {
+		t.Fatalf("node2 failed to join: %v", err)
+	}
+	waitFor(t, 3*time.Second, func() bool { return knowsAlive(g1, "node2") }, "node1 to learn node2")
+	_ = before.Shutdown()
+
+	// The device comes back on another network with a fresh incarnation
+	after, err := NewGossiper(cfg, network.NewTransport("192.168.1.2"), NewCodec())
+	if err != nil {
+		t.Fatalf("failed to create gossiper: %v", err)
+	}
+	if err := after.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start gossiper: %v", err)
+	}
+	t.Cleanup(func() { _ = after.Shutdown() })
+	if err := after.Join("node1"); err != nil {
+		t.Fatalf("node2 failed to rejoin: %v", err)
+	}
+
+	waitFor(t, 3*time.Second, func() bool {
+		node, exists := g1.Members().GetNode("node2")
+		return exists && node.State == types.StateAlive && node.Address == "192.168.1.2"
+	}, "node1 to learn node2's new address")
+}
+
+func TestGossiper_DuplicateIDFightEnds(t *testing.T) {
+	// Two devices share an ID. A third node reports the conflict and stops
+	// relaying one of them, so their refutations stop climbing
+	network := NewTestNetwork()
+	g3 := newTestGossiper(t, network, "node3")
+	sub := g3.Members().Subscribe(64)
+	defer sub.Close()
+
+	cfg := testGossiperConfig("dup")
+	devices := []Gossiper{
+		newTestGossiperWithConfig(t, cfg, network.NewTransport("dup-a"), NewCodec()),
+		newTestGossiperWithConfig(t, cfg, network.NewTransport("dup-b"), NewCodec()),
+	}
+	for _, g := range devices {
+		if err := g.Join("node3"); err != nil {
+			t.Fatalf("failed to join: %v", err)
+		}
+	}
+
+	waitFor(t, 3*time.Second, func() bool {
+		for {
+			select {
+			case event := <-sub.Events():
+				if event.Type == EventConflict {
+					return true
+				}
+			default:
+				return false
+			}
+		}
+	}, "node3 to report the conflict")
+
+	incarnations := func() (sum uint64) {
+		for _, g := range devices {
+			local, _ := g.Members().LocalNode()
+			sum += local.Incarnation
+		}
+		return sum
+	}
+	settled := func() bool {
+		before := incarnations()
+		time.Sleep(10 * cfg.FailureDetector.ProbeInterval)
+		return incarnations() == before
+	}
+	waitFor(t, 5*time.Second, settled, "incarnations to stop climbing")
+}
+
+func TestGossiper_LocalitySelectionConverges(t *testing.T) {
+	network := NewTestNetwork()
+	sites := map[string]string{"node1": "a", "node2": "a", "node3": "b", "node4": "b"}
+
+	var gossipers []Gossiper
+	for _, id := range []string{"node1", "node2", "node3", "node4"} {
+		cfg := testGossiperConfig(id)
+		cfg.Selection.Strategy = config.SelectLocality
+		cfg.Selection.LocalityLabel = "site"
+		gossipers = append(gossipers, newTestGossiperWithConfig(t, cfg, network.NewTransport(id), NewCodec(),
+			WithNodeMetadata(types.NodeMetadata{Labels: map[string]string{"site": sites[id]}})))
+	}
+
+	for _, g := range gossipers[1:] {
+		if err := g.Join("node1"); err != nil {
+			t.Fatalf("failed to join: %v", err)
+		}
+	}
+
+	all := []types.NodeID{"node1", "node2", "node3", "node4"}
+	for _, g := range gossipers {
+		waitFor(t, 3*time.Second, func() bool { return knowsAlive(g, all...) }, "membership to converge")
+	}
+}
+
+func TestGossiper_LocalitySelectionLimitsRemoteGossip(t *testing.T) {
+	// Two sites of five nodes. Every node still probes the other site, but
+	// only about RemoteFraction of the gossip crosses over to it
+	network := NewTestNetwork()
+	site := func(id string) string { return id[:1] }
+
+	var ids []types.NodeID
+	gossipers := make(map[string]*gossiper)
+	transports := make(map[string]*recordingTransport)
+	for _, s := range []string{"a", "b"} {
+		for i := range 5 {
+			id := fmt.Sprintf("%s%d", s, i)
+			cfg := testGossiperConfig(id)
+			cfg.Selection.Strategy = config.SelectLocality
+			cfg.Selection.LocalityLabel = "site"
+			cfg.Selection.RemoteFraction = 0.2
+			cfg.Gossip.GossipInterval = 20 * time.Millisecond
+			cfg.Gossip.ChecksumSyncInterval = 100 * time.Millisecond
+			cfg.FailureDetector.ProbeInterval = 200 * time.Millisecond // ten nodes are slow to answer under -race
+			cfg.FailureDetector.ProbeTimeout = 100 * time.Millisecond
+			transports[id] = &recordingTransport{Transport: network.NewTransport(id)}
+			gossipers[id] = newTestGossiperWithConfig(t, cfg, transports[id], NewCodec(),
+				WithStreamTransport(network.NewStreamTransport(id)),
+				WithNodeMetadata(types.NodeMetadata{Labels: map[string]string{"site": s}}))
+			ids = append(ids, types.NodeID(id))
+		}
+	}
+	for id, g := range gossipers {
+		if id != "a0" {
+			if err := g.Join("a0"); err != nil {
+				t.Fatalf("failed to join: %v", err)
+			}
+		}
+	}
+	for _, g := range gossipers {
+		waitFor(t, 3*time.Second, func() bool { return knowsAlive(g, ids...) }, "membership to converge")
+	}
+
+	// Count the entries sent once everyone knows everyone, while every
+	// node publishes new metadata
+	start := make(map[string]int)
+	for id, transport := range transports {
+		start[id] = len(transport.datagrams())
+	}
+	for round := range 5 {
+		for id, g := range gossipers {
+			meta := types.NodeMetadata{Labels: map[string]string{"site": site(id)}, Priority: round}
+			if err := g.UpdateMetadata(meta); err != nil {
+				t.Fatalf("UpdateMetadata failed: %v", err)
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	// Stop sending before decoding, which is slow under -race
+	for _, g := range gossipers {
+		_ = g.Shutdown()
+	}
+
+	var remote, total int
+	for id, transport := range transports {
+		sent, addrs := transport.sent[start[id]:], transport.addrs[start[id]:]
+
+		for i, data := range sent {
+			msgs, err := DecodeMessages(gossipers[id].codec, data)
+			if err != nil {
+				t.Fatalf("DecodeMessages failed: %v", err)
+			}
+			for _, msg := range msgs {
+				n := 0
+				switch m := msg.(type) {
+				case *Gossip:
+					n = len(m.Gossip)
+				case *Ping:
+					n = len(m.Gossip)
+				case *Ack:
+					n = len(m.Gossip)
+				}
+				total += n
+				if site(addrs[i]) != site(id) {
+					remote += n
+				}
+			}
+		}
+	}
+
+	// A uniform choice would send 5 in 9 entries to the other site
+	if total == 0 || remote == 0 || float64(remote)/float64(total) > 0.35 {
+		t.Errorf("%d of %d entries crossed sites, want about 20%%", remote, total)
+	}
+}
+
+func TestGossiper_ProbesCarryGossipToPeersWithoutFanOut(t *testing.T) {
+	// Peers that do not advertise it may predate Gossip messages
+	tests := map[string]struct {
+		meta types.NodeMetadata
+		want bool
+	}{
+		"decodes gossip":         {types.NodeMetadata{Gossip: true}, false},
+		"does not decode gossip": {types.NodeMetadata{}, true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			network := NewTestNetwork()
+			cfg := testGossiperConfig("node1")
+			cfg.Gossip.GossipInterval = time.Hour
+			transport := &recordingTransport{Transport: network.NewTransport("node1")}
+			g := newTestGossiperWithConfig(t, cfg, transport, NewCodec())
+			network.NewTransport("node2")
+			addPeer(g, tt.meta)
+			if local, _ := g.members.LocalNode(); !local.Metadata.Gossip {
+				t.Error("node1 does not advertise that it decodes Gossip messages")
+			}
+
+			g.broadcasts.Queue(GossipEntry{NodeID: "node3", Address: "node3", State: types.StateAlive, Incarnation: 1})
+			if err := g.send("node2", &Ping{MessageHeader: g.detector.header(MessageTypePing, 1), Target: "node2"}); err != nil {
+				t.Fatalf("send failed: %v", err)
+			}
+
+			carried := false
+			for _, data := range transport.datagramsTo("node2") {
+				msgs, err := DecodeMessages(g.codec, data)
+				if err != nil {
+					t.Fatalf("DecodeMessages failed: %v", err)
+				}
+				for _, msg := range msgs {
+					if ping, ok := msg.(*Ping); ok && len(ping.Gossip) > 0 {
+						carried = true
+					}
+				}
+			}
+			if carried != tt.want {
+				t.Errorf("ping carried gossip = %v, want %v", carried, tt.want)
+			}
+		})
+	}
+}
+
+func TestGossiper_InvalidSelectionConfig(t *testing.T) {
+	cfg := testGossiperConfig("node1")
+	cfg.Selection.Strategy = config.SelectLocality // without a locality label
+
+	if _, err := NewGossiper(cfg, NewTestNetwork().NewTransport("node1"), NewCodec()); err == nil {
+		t.Error("NewGossiper accepted locality selection without a label")
+	}
+}
@@ -0,0 +1,110 @@
+package gossip
+
+import (
+	"maps"
+
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+// labelIndex maps every label key and value to the members carrying it, so
+// selector queries only look at members that can possibly match
+// It is not safe for concurrent use; the membership guards it with its lock
+type labelIndex struct {
+	byLabel map[string]map[string]map[types.NodeID]struct{} // key -> value -> members
+	labels  map[types.NodeID]map[string]string              // what each member is indexed under
+}
+
+func newLabelIndex() *labelIndex {
+	return &labelIndex{
+		byLabel: make(map[string]map[string]map[types.NodeID]struct{}),
+		labels:  make(map[types.NodeID]map[string]string),
+	}
+}
+
+// set indexes id under labels, replacing whatever it was indexed under
+func (x *labelIndex) set(id types.NodeID, labels map[string]string) {
+	x.delete(id)
+
+	for key, value := range labels {
+		values, exists := x.byLabel[key]
+		if !exists {
+			values = make(map[string]map[types.NodeID]struct{})
+			x.byLabel[key] = values
+		}
+		ids, exists := values[value]
+		if !exists {
+			ids = make(map[types.NodeID]struct{})
+			values[value] = ids
+		}
+		ids[id] = struct{}{}
+	}
+	x.labels[id] = maps.Clone(labels)
+}
+
+// delete drops id from the index
+func (x *labelIndex) delete(id types.NodeID) {
+	for key, value := range x.labels[id] {
+		ids := x.byLabel[key][value]
+		delete(ids, id)
+		if len(ids) == 0 {
+			delete(x.byLabel[key], value)
+		}
+		if len(x.byLabel[key]) == 0 {
+			delete(x.byLabel, key)
+		}
+	}
+	delete(x.labels, id)
+}
+
+// candidates returns the members that can match selector, or false if the
+// selector has no requirement the index can narrow by and every member has
+// to be checked
+func (x *labelIndex) candidates(selector labelSelector) (map[types.NodeID]struct{}, bool) {
+	var result map[types.NodeID]struct{}
+	narrowed := false
+
+	for _, req := range selector {
+		var matching map[types.NodeID]struct{}
+		switch req.op {
+		case opEquals, opIn:
+			matching = x.withValues(req.key, req.values)
+		case opExists:
+			matching = x.withValues(req.key, nil)
+		default:
+			continue // negative requirements match members missing from the index
+		}
+
+		if !narrowed {
+			result, narrowed = matching, true
+			continue
+		}
+		for id := range result {
+			if _, ok := matching[id]; !ok {
+				delete(result, id)
+			}
+		}
+	}
+	return result, narrowed
+}
+
+// withValues returns the members whose key label is one of values, or
+// carries key at all when values is nil
+func (x *labelIndex) withValues(key string, values []string) map[types.NodeID]struct{} {
+	ids := make(map[types.NodeID]struct{})
+	add := func(members map[types.NodeID]struct{}) {
+		for id := range members {
+			ids[id] = struct{}{}
+		}
+	}
+
+	if values == nil {
+		for _, members := range x.byLabel[key] {
+			add(members)
+		}
+		return ids
+	}
+	for _, value := range values {
+		add(x.byLabel[key][value])
+	}
+	return ids
+}
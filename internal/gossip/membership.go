@@ -1,38 +1,158 @@
 package gossip
 
 import (
+	"encoding/binary"
+	"hash/fnv"
+	"maps"
 	"math/rand"
 	"slices"
 	"sync"
 	"time"
 
+	"github.com/michael-martinez-dev/adaptive-hive/internal/config"
 	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
 )
 
 type Membership interface {
 	GetNode(id types.NodeID) (types.Node, bool)
+	GetNodeByAddress(addr string) (types.Node, bool)
 	AllNodes() []types.Node
 	GetNodesByState(state types.NodeState) []types.Node
+	QueryNodes(selector LabelSelector) []types.Node
 	Len() int
 	Merge(entry GossipEntry) bool
 	Suspect(id types.NodeID) bool
 	Dead(id types.NodeID) bool
 	Remove(id types.NodeID)
+	Reap(retention time.Duration) int
 	RandomNode(...types.NodeID) (types.Node, bool)
 	RandomNodes(k int, exclude ...types.NodeID) []types.Node
+	LocalNode() (types.Node, bool)
+	LeaveLocal() (GossipEntry, bool)
+	UpdateLocalMetadata(meta types.NodeMetadata) (GossipEntry, bool)
+	UpdateLocalAddress(addr string) (GossipEntry, bool)
+	Restore(nodes []types.Node)
+
+	// Subscribe starts delivering change events to a new subscription that
+	// buffers up to buffer events
+	Subscribe(buffer int) Subscription
+
+	// Checksum returns an order-independent digest of the ID, state and
+	// incarnation of every live member. Two memberships that agree on the
+	// live members have the same checksum
+	Checksum() uint64
 }
 
 type membership struct {
 	mu    sync.RWMutex
 	nodes map[string]*types.Node
+
+	local    types.NodeID // empty when the membership does not represent a running node
+	onRefute func(GossipEntry)
+	events   *eventHub
+	labels   *labelIndex
+	addrs    *addressIndex
+	checksum uint64 // XOR of memberHash over every member
+	conflict config.ConflictPolicy
+
+	// moves holds the recent moves of each live member, and impostors the
+	// address that lost a conflict over each member's ID
+	moves          map[types.NodeID]*addressMoves
+	impostors      map[types.NodeID]impostor
+	conflictWindow time.Duration
+
+	// refuted holds the other addresses we refuted rumors about the local
+	// node from
+	refuted map[string]bool
+}
+
+// addressMoves tracks a live member's recent moves between addresses
+type addressMoves struct {
+	from  string      // address it last moved away from
+	backs []time.Time // moves back to from within the conflict window
 }
 
-func NewMembership() Membership {
-	return &membership{
-		nodes: map[string]*types.Node{},
+// impostor is an address that lost a fight over a member's ID
+type impostor struct {
+	address string
+	until   time.Time // its claims are ignored until then
+}
+
+// conflictMoveBacks is how many moves back to the previous address within
+// the conflict window tell two nodes sharing an ID apart from a node that
+// moved away and came back
+const conflictMoveBacks = 3
+
+// MembershipOption configures optional Membership behaviour
+type MembershipOption func(*membership)
+
+// WithLocalNode marks node as the member this process runs as. Rumors that
+// it is suspect or dead are refuted by bumping its incarnation instead of
+// being applied
+func WithLocalNode(node types.Node) MembershipOption {
+	return func(m *membership) {
+		node.State = types.StateAlive
+		node.Metadata = cloneMetadata(node.Metadata)
+		node.LastUpdated = time.Now()
+		m.local = node.ID
+		node.MetadataIncarnation = node.Incarnation
+		m.nodes[string(node.ID)] = &node
+		m.labels.set(node.ID, node.Metadata.Labels)
+		m.addrs.set(node.ID, node.Address)
+		m.checksum ^= memberHash(&node)
 	}
 }
 
+// WithRefuteHandler registers fn to receive the alive entry produced every
+// time the local node refutes a rumor, so it can be broadcast immediately
+func WithRefuteHandler(fn func(GossipEntry)) MembershipOption {
+	return func(m *membership) {
+		m.onRefute = fn
+	}
+}
+
+// WithConflictPolicy sets how a conflicting claim to a remote member's ID
+// is resolved. Claims to the local node's ID are always refuted
+func WithConflictPolicy(policy config.ConflictPolicy) MembershipOption {
+	return func(m *membership) {
+		m.conflict = policy
+	}
+}
+
+// WithConflictWindow sets how recent the moves of a member between two
+// addresses must be to count as a conflict over its ID
+func WithConflictWindow(window time.Duration) MembershipOption {
+	return func(m *membership) {
+		m.conflictWindow = window
+	}
+}
+
+// withEventHub makes the membership publish to hub, so its owner can
+// publish events of its own on the same subscriptions
+func withEventHub(hub *eventHub) MembershipOption {
+	return func(m *membership) {
+		m.events = hub
+	}
+}
+
+func NewMembership(opts ...MembershipOption) Membership {
+	m := &membership{
+		nodes:  map[string]*types.Node{},
+		events: newEventHub(),
+		labels: newLabelIndex(),
+		addrs:  newAddressIndex(),
+
+		moves:          map[types.NodeID]*addressMoves{},
+		impostors:      map[types.NodeID]impostor{},
+		refuted:        map[string]bool{},
+		conflictWindow: 30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
 func (m *membership) GetNode(id types.NodeID) (types.Node, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -41,62 +161,520 @@ func (m *membership) GetNode(id types.NodeID) (types.Node, bool) {
 	if !exists {
 		return types.Node{}, false
 	}
-	return *node, exists
+	return snapshot(node), exists
+}
+
+// GetNodeByAddress returns the member at addr, preferring a live one when a
+// tombstone shares its address
+func (m *membership) GetNodeByAddress(addr string) (types.Node, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var found *types.Node
+	for id := range m.addrs.at(addr) {
+		node := m.nodes[string(id)]
+		if found == nil || isTombstone(found.State) && !isTombstone(node.State) {
+			found = node
+		}
+	}
+	if found == nil {
+		return types.Node{}, false
+	}
+	return snapshot(found), true
 }
 
 func (m *membership) AllNodes() []types.Node {
-	return make([]types.Node, 0)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	nodes := make([]types.Node, 0, len(m.nodes))
+	for _, node := range m.nodes {
+		nodes = append(nodes, snapshot(node))
+	}
+	return nodes
 }
 
 func (m *membership) GetNodesByState(state types.NodeState) []types.Node {
-	return make([]types.Node, 0)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	nodes := make([]types.Node, 0)
+	for _, node := range m.nodes {
+		if node.State == state {
+			nodes = append(nodes, snapshot(node))
+		}
+	}
+	return nodes
+}
+
+// QueryNodes returns every member, in any state, whose labels match selector
+func (m *membership) QueryNodes(selector LabelSelector) []types.Node {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	nodes := make([]types.Node, 0)
+	if parsed, ok := selector.(labelSelector); ok {
+		if ids, narrowed := m.labels.candidates(parsed); narrowed {
+			for id := range ids {
+				if node := m.nodes[string(id)]; selector.Matches(node.Metadata.Labels) {
+					nodes = append(nodes, snapshot(node))
+				}
+			}
+			return nodes
+		}
+	}
+
+	for _, node := range m.nodes {
+		if selector.Matches(node.Metadata.Labels) {
+			nodes = append(nodes, snapshot(node))
+		}
+	}
+	return nodes
 }
 
 func (m *membership) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	return len(m.nodes)
 }
 
 func (m *membership) Merge(entry GossipEntry) bool {
+	if m.local != "" && entry.NodeID == m.local {
+		m.mergeLocal(entry)
+		return false
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	node, exists := m.nodes[string(entry.NodeID)]
 	if !exists {
+		if !isLiveState(entry.State) {
+			return false // nothing to learn, and it could revive a reaped tombstone
+		}
 		node = &types.Node{
 			ID:          entry.NodeID,
 			Address:     entry.Address,
 			State:       entry.State,
 			Incarnation: entry.Incarnation,
+			Cluster:     entry.ClusterID,
 			LastUpdated: time.Now(),
 		}
+		if entry.Metadata != nil {
+			node.Metadata = cloneMetadata(*entry.Metadata)
+			node.MetadataIncarnation = metadataIncarnation(entry)
+		}
 		m.nodes[string(entry.NodeID)] = node
+		m.notify(types.Node{}, false, node)
 		return true
 	}
 
-	if entry.Incarnation > node.Incarnation {
-		node.State = entry.State
-		node.Incarnation = entry.Incarnation
+	if isTombstone(node.State) && entry.Incarnation <= node.Incarnation {
+		return false // only the node itself can come back, with a new incarnation
+	}
+	if isConflicting(node, entry) {
+		switch {
+		case m.isImpostor(node, entry):
+			return false // lost the fight over this ID already
+		case entry.Incarnation == node.Incarnation:
+			return m.mergeConflict(node, entry)
+		case m.fightsOver(node, entry):
+			// Two nodes are fighting over the ID. The loser's claims are
+			// dropped for a while instead of being gossiped on
+			loser := impostor{address: entry.Address, until: time.Now().Add(m.conflictWindow)}
+			if m.conflict == config.ConflictPreferNew {
+				loser.address = node.Address
+			}
+			m.impostors[node.ID] = loser
+			delete(m.moves, node.ID)
+			return m.mergeConflict(node, entry)
+		}
+	}
+	// Metadata is owned by the node it describes, which bumps its
+	// incarnation to publish a change, so the copy published last wins
+	newerMeta := entry.Metadata != nil &&
+		(node.MetadataIncarnation == 0 || metadataIncarnation(entry) > node.MetadataIncarnation)
+
+	if !supersedes(node, entry.State, entry.Incarnation) {
+		if !newerMeta || isConflicting(node, entry) {
+			return false // a stale claim from elsewhere says nothing about it
+		}
+		// Members first heard of through an entry without metadata, such
+		// as a suspicion, still need it
+		before := snapshot(node)
+		node.Metadata = cloneMetadata(*entry.Metadata)
+		node.MetadataIncarnation = metadataIncarnation(entry)
+		node.LastUpdated = time.Now()
+		m.notify(before, true, node)
 		return true
 	}
+	before := snapshot(node)
+
+	// A node that moved announces its new address with a new incarnation
+	if entry.Address != "" && entry.Incarnation > node.Incarnation {
+		if isLiveState(node.State) && entry.Address != node.Address {
+			if m.moves[node.ID] == nil {
+				m.moves[node.ID] = &addressMoves{}
+			}
+			m.moves[node.ID].from = node.Address
+		}
+		node.Address = entry.Address
+	}
+
+	if newerMeta {
+		node.Metadata = cloneMetadata(*entry.Metadata)
+		node.MetadataIncarnation = metadataIncarnation(entry)
+	}
+	if entry.ClusterID != "" {
+		node.Cluster = entry.ClusterID
+	}
+	node.State = entry.State
+	node.Incarnation = entry.Incarnation
+	node.LastUpdated = time.Now()
+	m.notify(before, true, node)
+	return true
+}
+
+func (m *membership) LocalNode() (types.Node, bool) {
+	if m.local == "" {
+		return types.Node{}, false
+	}
+	return m.GetNode(m.local)
+}
+
+// LeaveLocal marks the local node as left at a new incarnation, so the
+// announcement supersedes every earlier rumor about it. Once left, the
+// local node no longer refutes anything. Returns false without a local node
+func (m *membership) LeaveLocal() (GossipEntry, bool) {
+	if m.local == "" {
+		return GossipEntry{}, false
+	}
 
-	if entry.Incarnation == node.Incarnation && entry.State > node.State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node := m.nodes[string(m.local)]
+	if node.State != types.StateLeft {
+		before := snapshot(node)
+		node.Incarnation++
+		node.State = types.StateLeft
+		node.LastUpdated = time.Now()
+		m.notify(before, true, node)
+	}
+	return m.localEntry(node), true
+}
+
+// fightsOver reports whether entry moves the live node back to the address
+// it last moved away from, for the conflictMoveBacks-th time within the
+// conflict window. Two nodes sharing an ID refute each other's claims with
+// ever higher incarnations, so to everyone else the ID looks like it keeps
+// moving between their addresses. A node that really moves rarely comes
+// back more than once. Must be called with the lock held
+func (m *membership) fightsOver(node *types.Node, entry GossipEntry) bool {
+	moves := m.moves[node.ID]
+	if !isLiveState(node.State) || entry.Incarnation <= node.Incarnation || moves == nil || moves.from != entry.Address {
+		return false
+	}
+
+	now := time.Now()
+	moves.backs = slices.DeleteFunc(moves.backs, func(at time.Time) bool { return now.Sub(at) > m.conflictWindow })
+	moves.backs = append(moves.backs, now)
+	return len(moves.backs) >= conflictMoveBacks
+}
+
+// isImpostor reports whether entry comes from the address that lost a fight
+// over node's ID. Once the conflict window has passed, a newer claim from
+// it is taken as the node moving there and clears it. Must be called with
+// the lock held
+func (m *membership) isImpostor(node *types.Node, entry GossipEntry) bool {
+	loser, ok := m.impostors[node.ID]
+	if !ok || loser.address != entry.Address {
+		return false
+	}
+	if time.Now().Before(loser.until) || entry.Incarnation <= node.Incarnation {
+		return true
+	}
+	delete(m.impostors, node.ID)
+	return false
+}
+
+// mergeConflict handles a claim to node's ID from a different address that
+// can only come from a second node sharing the ID
+// Must be called with the lock held
+func (m *membership) mergeConflict(node *types.Node, entry GossipEntry) bool {
+	before := snapshot(node)
+	m.events.publish(MembershipEvent{Type: EventConflict, Before: before, After: nodeFromEntry(entry)})
+
+	if m.conflict != config.ConflictPreferNew {
+		return false
+	}
+
+	node.Address = entry.Address
+	if entry.Metadata != nil {
+		node.Metadata = cloneMetadata(*entry.Metadata)
+	}
+	if supersedes(node, entry.State, entry.Incarnation) {
 		node.State = entry.State
 		node.Incarnation = entry.Incarnation
-		return true
 	}
+	node.LastUpdated = time.Now()
+	m.notify(before, true, node)
+	return true
+}
 
-	return false
+// UpdateLocalMetadata replaces the local node's metadata and bumps its
+// incarnation so the change supersedes every earlier entry about it.
+// Returns false without a local node or once it has left
+func (m *membership) UpdateLocalMetadata(meta types.NodeMetadata) (GossipEntry, bool) {
+	if m.local == "" {
+		return GossipEntry{}, false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node := m.nodes[string(m.local)]
+	if node.State == types.StateLeft {
+		return GossipEntry{}, false
+	}
+
+	before := snapshot(node)
+	node.Incarnation++
+	node.Metadata = cloneMetadata(meta)
+	node.MetadataIncarnation = node.Incarnation
+	node.LastUpdated = time.Now()
+	m.notify(before, true, node)
+	return m.localEntry(node), true
 }
 
+// UpdateLocalAddress moves the local node to addr, bumping its incarnation
+// like UpdateLocalMetadata. Returns false without a local node or once it
+// has left
+func (m *membership) UpdateLocalAddress(addr string) (GossipEntry, bool) {
+	if m.local == "" {
+		return GossipEntry{}, false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node := m.nodes[string(m.local)]
+	if node.State == types.StateLeft {
+		return GossipEntry{}, false
+	}
+
+	before := snapshot(node)
+	node.Incarnation++
+	node.Address = addr
+	node.LastUpdated = time.Now()
+	m.notify(before, true, node)
+	return m.localEntry(node), true
+}
+
+// Restore loads nodes saved by an earlier run. The local node resumes past
+// its saved incarnation, so it supersedes anything said about it while it
+// was away. Dead and left members are restored as saved, so stale gossip
+// cannot revive them before they are reaped. Other members are merged as if
+// they had been gossiped
+func (m *membership) Restore(nodes []types.Node) {
+	for _, saved := range nodes {
+		if m.local == "" || saved.ID != m.local {
+			if isTombstone(saved.State) {
+				m.restoreTombstone(saved)
+			} else {
+				_ = m.Merge(fullEntryFromNode(saved))
+			}
+			continue
+		}
+
+		m.mu.Lock()
+		node := m.nodes[string(m.local)]
+		if saved.Incarnation >= node.Incarnation {
+			// Our metadata may differ from the saved run's, so it is
+			// published again at the new incarnation
+			before := snapshot(node)
+			node.Incarnation = saved.Incarnation + 1
+			node.MetadataIncarnation = node.Incarnation
+			node.LastUpdated = time.Now()
+			m.notify(before, true, node)
+		}
+		m.mu.Unlock()
+	}
+}
+
+// restoreTombstone adds a saved dead or left member unless it is already
+// known. It keeps its saved LastUpdated, so it is reaped on the same
+// schedule as if the run had not stopped, and publishes no event since
+// nothing about it changed
+func (m *membership) restoreTombstone(saved types.Node) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.nodes[string(saved.ID)]; exists {
+		return
+	}
+	node := saved
+	node.Metadata = cloneMetadata(saved.Metadata)
+	m.nodes[string(node.ID)] = &node
+	m.labels.set(node.ID, node.Metadata.Labels)
+	m.addrs.set(node.ID, node.Address)
+}
+
+// mergeLocal handles a rumor about the local node. Only this node may change
+// its own state, so any rumor that is not an echo of the current alive
+// state is refuted with a higher incarnation. A rumor from another address
+// may be about our own earlier life, such as before a restart on a new
+// address, so it is only a conflict once that address answers a refutation
+// with a live claim of its own
+func (m *membership) mergeLocal(entry GossipEntry) {
+	m.mu.Lock()
+	node := m.nodes[string(m.local)]
+
+	if node.State == types.StateLeft {
+		m.mu.Unlock()
+		return // we are leaving, so there is nothing to refute
+	}
+	if entry.Incarnation < node.Incarnation {
+		m.mu.Unlock()
+		return // stale rumor, already superseded
+	}
+	elsewhere := isConflicting(node, entry)
+	if elsewhere && isLiveState(entry.State) && m.refuted[entry.Address] {
+		// Another node is using our ID. We never give it up, so the claim
+		// is refuted like any other rumor and surfaced to subscribers
+		m.events.publish(MembershipEvent{Type: EventConflict, Before: snapshot(node), After: nodeFromEntry(entry)})
+	} else if !elsewhere && entry.Incarnation == node.Incarnation && entry.State == types.StateAlive {
+		m.mu.Unlock()
+		return // echo of our own alive broadcast
+	}
+
+	before := snapshot(node)
+	node.Incarnation = entry.Incarnation + 1
+	node.State = types.StateAlive
+	node.LastUpdated = time.Now()
+	m.notify(before, true, node)
+	refutation := m.localEntry(node)
+	if elsewhere {
+		// Members that took the other address or its metadata need ours
+		m.refuted[entry.Address] = true
+		refutation = fullEntryFromNode(*node)
+	}
+	m.mu.Unlock()
+
+	if m.onRefute != nil {
+		m.onRefute(refutation)
+	}
+}
+
+// Suspect marks id as suspect at its current incarnation. Returns false if
+// the node is unknown, is the local node, or is already suspect or worse
 func (m *membership) Suspect(id types.NodeID) bool {
-	return false
+	return m.transition(id, types.StateSuspect)
 }
 
+// Dead marks id as dead at its current incarnation. Returns false if the
+// node is unknown, is the local node, or is already dead or left
 func (m *membership) Dead(id types.NodeID) bool {
-	return false
+	return m.transition(id, types.StateDead)
 }
 
+// Remove forgets id entirely. The local node cannot be removed
 func (m *membership) Remove(id types.NodeID) {
+	if m.local != "" && id == m.local {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node, exists := m.nodes[string(id)]
+	if !exists {
+		return
+	}
+	m.remove(node)
+}
+
+// Reap removes dead and left members whose tombstones are older than
+// retention and returns how many were removed. The local node is never
+// reaped
+func (m *membership) Reap(retention time.Duration) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cutoff := time.Now().Add(-retention)
+	reaped := 0
+	for _, node := range m.nodes {
+		if node.ID == m.local || !isTombstone(node.State) || node.LastUpdated.After(cutoff) {
+			continue
+		}
+		m.remove(node)
+		reaped++
+	}
+	return reaped
+}
+
+// remove deletes node and announces the reap
+// Must be called with the lock held
+func (m *membership) remove(node *types.Node) {
+	delete(m.nodes, string(node.ID))
+	m.labels.delete(node.ID)
+	m.addrs.delete(node.ID)
+	m.checksum ^= memberHash(node)
+	m.events.publish(MembershipEvent{Type: EventReap, Before: snapshot(node)})
+}
+
+// transition moves id to state at its current incarnation, following the
+// same precedence as Merge
+func (m *membership) transition(id types.NodeID, state types.NodeState) bool {
+	if m.local != "" && id == m.local {
+		return false // only refutation may change the local node
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node, exists := m.nodes[string(id)]
+	if !exists || !supersedes(node, state, node.Incarnation) {
+		return false
+	}
+
+	before := snapshot(node)
+	node.State = state
+	node.LastUpdated = time.Now()
+	m.notify(before, true, node)
+	return true
+}
+
+func (m *membership) Subscribe(buffer int) Subscription {
+	return m.events.subscribe(buffer)
+}
+
+func (m *membership) Checksum() uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.checksum
+}
+
+// notify records that node changed from before: it reindexes node's labels
+// and address, updates the checksum and publishes the event for the change,
+// if any. Must be called with the lock held, so events arrive in order
+func (m *membership) notify(before types.Node, existed bool, node *types.Node) {
+	m.labels.set(node.ID, node.Metadata.Labels)
+	m.addrs.set(node.ID, node.Address)
+	if !isLiveState(node.State) {
+		// Whoever claims the ID next starts afresh
+		delete(m.moves, node.ID)
+		delete(m.impostors, node.ID)
+	}
+	if existed {
+		m.checksum ^= memberHash(&before)
+	}
+	m.checksum ^= memberHash(node)
+	if typ, ok := classifyChange(before, existed, *node); ok {
+		m.events.publish(MembershipEvent{Type: typ, Before: before, After: snapshot(node)})
+	}
 }
 
 func (m *membership) RandomNode(exclude ...types.NodeID) (types.Node, bool) {
@@ -108,11 +686,12 @@ func (m *membership) RandomNode(exclude ...types.NodeID) (types.Node, bool) {
 }
 
 func (m *membership) RandomNodes(k int, exclude ...types.NodeID) []types.Node {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	if k < 1 || len(m.nodes) == 0 {
 		return make([]types.Node, 0)
 	}
-	m.mu.RLock()
-	defer m.mu.RUnlock()
 	rnodes := make([]types.Node, 0)
 	for _, val := range m.nodes {
 		if isExcluded(exclude, val) {
@@ -122,7 +701,7 @@ func (m *membership) RandomNodes(k int, exclude ...types.NodeID) []types.Node {
 			continue
 		}
 
-		rnodes = append(rnodes, *val)
+		rnodes = append(rnodes, snapshot(val))
 	}
 	rnodes = randomize(rnodes)
 	if len(rnodes) > k {
@@ -131,6 +710,16 @@ func (m *membership) RandomNodes(k int, exclude ...types.NodeID) []types.Node {
 	return rnodes
 }
 
+// supersedes reports whether state at incarnation replaces node's current
+// state: a higher incarnation always wins, and at the same incarnation the
+// worse state wins (alive < suspect < dead < left)
+func supersedes(node *types.Node, state types.NodeState, incarnation uint64) bool {
+	if incarnation != node.Incarnation {
+		return incarnation > node.Incarnation
+	}
+	return state > node.State
+}
+
 func isExcluded(excludedNodes []types.NodeID, node *types.Node) bool {
 	return slices.Contains(excludedNodes, node.ID)
 }
@@ -139,7 +728,110 @@ func isLiveState(state types.NodeState) bool {
 	return state == types.StateAlive || state == types.StateSuspect
 }
 
+// memberHash is node's contribution to the membership checksum. Tombstones
+// contribute nothing, since members forget them at different times
+func memberHash(node *types.Node) uint64 {
+	if !isLiveState(node.State) {
+		return 0
+	}
+
+	var buf [9]byte
+	buf[0] = byte(node.State)
+	binary.BigEndian.PutUint64(buf[1:], node.Incarnation)
+
+	h := fnv.New64a()
+	h.Write([]byte(node.ID))
+	h.Write(buf[:])
+	return h.Sum64()
+}
+
+// isConflicting reports whether entry claims node's ID from another address
+func isConflicting(node *types.Node, entry GossipEntry) bool {
+	return entry.Address != "" && entry.Address != node.Address
+}
+
+// isTombstone reports whether state marks a member that is gone but still
+// remembered, so that stale gossip about it is rejected
+func isTombstone(state types.NodeState) bool {
+	return state == types.StateDead || state == types.StateLeft
+}
+
 func randomize(nodes []types.Node) []types.Node {
 	rand.Shuffle(len(nodes), func(i, j int) { nodes[i], nodes[j] = nodes[j], nodes[i] })
 	return nodes
 }
+
+// snapshot copies node so callers never share its labels with the membership
+func snapshot(node *types.Node) types.Node {
+	c := *node
+	c.Metadata = cloneMetadata(node.Metadata)
+	return c
+}
+
+// cloneMetadata deep-copies meta, whose labels map would otherwise be shared
+func cloneMetadata(meta types.NodeMetadata) types.NodeMetadata {
+	meta.Labels = maps.Clone(meta.Labels)
+	return meta
+}
+
+// nodeFromEntry builds the member entry describes
+func nodeFromEntry(entry GossipEntry) types.Node {
+	node := types.Node{
+		ID:          entry.NodeID,
+		Address:     entry.Address,
+		State:       entry.State,
+		Incarnation: entry.Incarnation,
+		Cluster:     entry.ClusterID,
+		LastUpdated: time.Now(),
+	}
+	if entry.Metadata != nil {
+		node.Metadata = cloneMetadata(*entry.Metadata)
+		node.MetadataIncarnation = metadataIncarnation(entry)
+	}
+	return node
+}
+
+// metadataIncarnation returns the incarnation entry's metadata was published
+// at. Older peers publish it with every entry that carries it
+func metadataIncarnation(entry GossipEntry) uint64 {
+	if entry.MetadataIncarnation == 0 {
+		return entry.Incarnation
+	}
+	return entry.MetadataIncarnation
+}
+
+// localEntry builds the entry announcing the local node, with its metadata
+// only if it changed at the current incarnation. Must be called with the
+// lock held
+func (m *membership) localEntry(node *types.Node) GossipEntry {
+	if node.MetadataIncarnation == node.Incarnation {
+		return fullEntryFromNode(*node)
+	}
+	return entryFromNode(*node)
+}
+
+// entryFromNode builds the gossip entry that announces node's current state.
+// Its metadata is left out: only the node itself publishes changes to it
+func entryFromNode(node types.Node) GossipEntry {
+	return GossipEntry{
+		NodeID:      node.ID,
+		Address:     node.Address,
+		State:       node.State,
+		Incarnation: node.Incarnation,
+		Timestamp:   time.Now().UnixNano(),
+		ClusterID:   node.Cluster,
+	}
+}
+
+// fullEntryFromNode is entryFromNode with node's metadata, for peers that
+// may never have heard of node
+func fullEntryFromNode(node types.Node) GossipEntry {
+	entry := entryFromNode(node)
+	if node.MetadataIncarnation == 0 {
+		return entry // we never learned it, so we have nothing to pass on
+	}
+	meta := cloneMetadata(node.Metadata)
+	entry.Metadata = &meta
+	entry.MetadataIncarnation = node.MetadataIncarnation
+	return entry
+}
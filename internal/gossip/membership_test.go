@@ -1,9 +1,12 @@
 package gossip
 
 import (
+	"slices"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/michael-martinez-dev/adaptive-hive/internal/config"
 	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
 )
 
@@ -259,14 +262,28 @@ func TestMembership_ConcurrentAccess(t *testing.T) {
 	wg.Wait()
 }
 
+// mergeEveryState adds a member named after each state it ends up in. Merge
+// ignores tombstones of unknown members, so the dead and left ones join
+// alive first and then move on
+func mergeEveryState(t *testing.T, membership Membership) {
+	t.Helper()
+	for _, id := range []types.NodeID{"alive", "suspect", "dead", "left"} {
+		_ = membership.Merge(GossipEntry{NodeID: id, State: types.StateAlive, Incarnation: 1})
+	}
+	membership.Suspect("suspect")
+	membership.Dead("dead")
+	_ = membership.Merge(GossipEntry{NodeID: "left", State: types.StateLeft, Incarnation: 2})
+
+	for _, state := range []types.NodeState{types.StateAlive, types.StateSuspect, types.StateDead, types.StateLeft} {
+		if node, _ := membership.GetNode(types.NodeID(state.String())); node.State != state {
+			t.Fatalf("%s member is %s", state, node.State)
+		}
+	}
+}
+
 func TestMembership_RandomNodeExcludesDeadAndLeft(t *testing.T) {
 	membership := NewMembership()
-
-	// Add one alive, one dead node
-	_ = membership.Merge(GossipEntry{NodeID: "alive", State: types.StateAlive, Incarnation: 1})
-	_ = membership.Merge(GossipEntry{NodeID: "dead", State: types.StateDead, Incarnation: 1})
-	_ = membership.Merge(GossipEntry{NodeID: "suspect", State: types.StateSuspect, Incarnation: 1})
-	_ = membership.Merge(GossipEntry{NodeID: "left", State: types.StateLeft, Incarnation: 1})
+	mergeEveryState(t, membership)
 
 	for range 100 {
 		node, ok := membership.RandomNode()
@@ -281,16 +298,12 @@ func TestMembership_RandomNodeExcludesDeadAndLeft(t *testing.T) {
 
 func TestMembership_RandomNodesExcludesDeadAndLeft(t *testing.T) {
 	membership := NewMembership()
-
-	_ = membership.Merge(GossipEntry{NodeID: "alive", State: types.StateAlive, Incarnation: 1})
-	_ = membership.Merge(GossipEntry{NodeID: "dead", State: types.StateDead, Incarnation: 1})
-	_ = membership.Merge(GossipEntry{NodeID: "suspect", State: types.StateSuspect, Incarnation: 1})
-	_ = membership.Merge(GossipEntry{NodeID: "left", State: types.StateLeft, Incarnation: 1})
+	mergeEveryState(t, membership)
 
 	for range 100 {
 		nodes := membership.RandomNodes(4)
 		if len(nodes) != 2 {
-			t.Fatalf("RandomNodes returned %d nodes, expected 3", len(nodes))
+			t.Fatalf("RandomNodes returned %d nodes, want 2", len(nodes))
 		}
 		for _, n := range nodes {
 			if n.State == types.StateDead || n.State == types.StateLeft {
@@ -322,3 +335,977 @@ func TestMembership_RandomNodeExcludesSpecified(t *testing.T) {
 		}
 	}
 }
+
+func TestMembership_LocalNodeRefutesSuspicion(t *testing.T) {
+	var refutations []GossipEntry
+	membership := NewMembership(
+		WithLocalNode(types.Node{ID: "local", Address: "10.0.0.1:1234", Incarnation: 1}),
+		WithRefuteHandler(func(entry GossipEntry) { refutations = append(refutations, entry) }),
+	)
+
+	changed := membership.Merge(GossipEntry{NodeID: "local", State: types.StateSuspect, Incarnation: 3})
+	if changed {
+		t.Error("rumor about the local node should not be applied")
+	}
+
+	local, ok := membership.LocalNode()
+	if !ok {
+		t.Fatal("local node does not exist as expected")
+	}
+	if local.State != types.StateAlive {
+		t.Errorf("state = %s, want alive", local.State)
+	}
+	if local.Incarnation != 4 {
+		t.Errorf("incarnation = %d, want 4", local.Incarnation)
+	}
+
+	if len(refutations) != 1 {
+		t.Fatalf("refutations = %d, want 1", len(refutations))
+	}
+	if refutations[0].State != types.StateAlive || refutations[0].Incarnation != 4 {
+		t.Errorf("refutation = %s@%d, want alive@4", refutations[0].State, refutations[0].Incarnation)
+	}
+	if refutations[0].Address != "10.0.0.1:1234" {
+		t.Errorf("refutation address = %q, want %q", refutations[0].Address, "10.0.0.1:1234")
+	}
+}
+
+func TestMembership_LocalNodeRefutesDeath(t *testing.T) {
+	refuted := 0
+	membership := NewMembership(
+		WithLocalNode(types.Node{ID: "local", Incarnation: 5}),
+		WithRefuteHandler(func(GossipEntry) { refuted++ }),
+	)
+
+	_ = membership.Merge(GossipEntry{NodeID: "local", State: types.StateDead, Incarnation: 5})
+
+	local, _ := membership.LocalNode()
+	if local.State != types.StateAlive || local.Incarnation != 6 {
+		t.Errorf("local = %s@%d, want alive@6", local.State, local.Incarnation)
+	}
+	if refuted != 1 {
+		t.Errorf("refutations = %d, want 1", refuted)
+	}
+}
+
+func TestMembership_LocalNodeIgnoresStaleAndEchoedRumors(t *testing.T) {
+	refuted := 0
+	membership := NewMembership(
+		WithLocalNode(types.Node{ID: "local", Incarnation: 5}),
+		WithRefuteHandler(func(GossipEntry) { refuted++ }),
+	)
+
+	// Suspicion of an incarnation we already moved past
+	_ = membership.Merge(GossipEntry{NodeID: "local", State: types.StateSuspect, Incarnation: 4})
+
+	// Our own alive broadcast gossiped back to us
+	_ = membership.Merge(GossipEntry{NodeID: "local", State: types.StateAlive, Incarnation: 5})
+
+	local, _ := membership.LocalNode()
+	if local.State != types.StateAlive || local.Incarnation != 5 {
+		t.Errorf("local = %s@%d, want alive@5", local.State, local.Incarnation)
+	}
+	if refuted != 0 {
+		t.Errorf("refutations = %d, want 0", refuted)
+	}
+}
+
+func TestMembership_NoLocalNode(t *testing.T) {
+	membership := NewMembership()
+
+	if _, ok := membership.LocalNode(); ok {
+		t.Error("LocalNode returned ok without a local node")
+	}
+}
+
+func TestMembership_LeaveLocal(t *testing.T) {
+	refuted := 0
+	membership := NewMembership(
+		WithLocalNode(types.Node{ID: "local", Incarnation: 3}),
+		WithRefuteHandler(func(GossipEntry) { refuted++ }),
+	)
+
+	entry, ok := membership.LeaveLocal()
+	if !ok {
+		t.Fatal("LeaveLocal returned not ok")
+	}
+	if entry.State != types.StateLeft || entry.Incarnation != 4 {
+		t.Errorf("entry = %s@%d, want left@4", entry.State, entry.Incarnation)
+	}
+
+	// A leaving node does not fight rumors about itself
+	_ = membership.Merge(GossipEntry{NodeID: "local", State: types.StateSuspect, Incarnation: 4})
+
+	local, _ := membership.LocalNode()
+	if local.State != types.StateLeft || local.Incarnation != 4 {
+		t.Errorf("local = %s@%d, want left@4", local.State, local.Incarnation)
+	}
+	if refuted != 0 {
+		t.Errorf("refutations = %d, want 0", refuted)
+	}
+}
+
+func TestMembership_AllNodesAndGetNodesByState(t *testing.T) {
+	membership := NewMembership()
+
+	_ = membership.Merge(GossipEntry{NodeID: "alive1", State: types.StateAlive, Incarnation: 1})
+	_ = membership.Merge(GossipEntry{NodeID: "alive2", State: types.StateAlive, Incarnation: 1})
+	_ = membership.Merge(GossipEntry{NodeID: "suspect", State: types.StateSuspect, Incarnation: 1})
+	_ = membership.Merge(GossipEntry{NodeID: "dead", State: types.StateAlive, Incarnation: 1})
+	_ = membership.Dead("dead")
+
+	if got := membership.AllNodes(); len(got) != 4 {
+		t.Errorf("AllNodes returned %d nodes, want 4", len(got))
+	}
+
+	cases := map[types.NodeState]int{
+		types.StateAlive:   2,
+		types.StateSuspect: 1,
+		types.StateDead:    1,
+		types.StateLeft:    0,
+	}
+	for state, want := range cases {
+		got := membership.GetNodesByState(state)
+		if len(got) != want {
+			t.Errorf("GetNodesByState(%s) returned %d nodes, want %d", state, len(got), want)
+		}
+		for _, node := range got {
+			if node.State != state {
+				t.Errorf("GetNodesByState(%s) returned %s node %s", state, node.State, node.ID)
+			}
+		}
+	}
+}
+
+func TestMembership_SnapshotsAreCopies(t *testing.T) {
+	membership := NewMembership()
+	_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 1})
+
+	nodes := membership.AllNodes()
+	nodes[0].State = types.StateDead
+
+	if node, _ := membership.GetNode("node1"); node.State != types.StateAlive {
+		t.Errorf("state = %s after mutating a snapshot, want alive", node.State)
+	}
+}
+
+func TestMembership_SuspectAndDeadTransitions(t *testing.T) {
+	membership := NewMembership()
+	_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 3})
+
+	if !membership.Suspect("node1") {
+		t.Fatal("Suspect of an alive node returned false")
+	}
+	if membership.Suspect("node1") {
+		t.Error("Suspect of a suspect node returned true")
+	}
+
+	node, _ := membership.GetNode("node1")
+	if node.State != types.StateSuspect || node.Incarnation != 3 {
+		t.Errorf("node1 = %s@%d, want suspect@3", node.State, node.Incarnation)
+	}
+
+	if !membership.Dead("node1") {
+		t.Fatal("Dead of a suspect node returned false")
+	}
+	if membership.Dead("node1") {
+		t.Error("Dead of a dead node returned true")
+	}
+	if membership.Suspect("node1") {
+		t.Error("Suspect of a dead node returned true")
+	}
+
+	node, _ = membership.GetNode("node1")
+	if node.State != types.StateDead || node.Incarnation != 3 {
+		t.Errorf("node1 = %s@%d, want dead@3", node.State, node.Incarnation)
+	}
+
+	// A higher incarnation still revives it
+	if !membership.Merge(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 4}) {
+		t.Error("alive merge at a higher incarnation was ignored")
+	}
+}
+
+func TestMembership_SuspectAndDeadIgnoreLeftAndUnknown(t *testing.T) {
+	membership := NewMembership()
+	_ = membership.Merge(GossipEntry{NodeID: "left", State: types.StateAlive, Incarnation: 1})
+	_ = membership.Merge(GossipEntry{NodeID: "left", State: types.StateLeft, Incarnation: 2})
+
+	if membership.Suspect("left") || membership.Dead("left") {
+		t.Error("a left node was marked suspect or dead")
+	}
+	if membership.Suspect("unknown") || membership.Dead("unknown") {
+		t.Error("an unknown node was marked suspect or dead")
+	}
+	if _, exists := membership.GetNode("unknown"); exists {
+		t.Error("Suspect or Dead created an unknown node")
+	}
+}
+
+func TestMembership_SuspectAndDeadIgnoreLocalNode(t *testing.T) {
+	membership := NewMembership(WithLocalNode(types.Node{ID: "local", Incarnation: 1}))
+
+	if membership.Suspect("local") || membership.Dead("local") {
+		t.Error("the local node was marked suspect or dead")
+	}
+
+	local, _ := membership.LocalNode()
+	if local.State != types.StateAlive {
+		t.Errorf("local state = %s, want alive", local.State)
+	}
+}
+
+func TestMembership_Remove(t *testing.T) {
+	membership := NewMembership(WithLocalNode(types.Node{ID: "local", Incarnation: 1}))
+	_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 1})
+
+	membership.Remove("node1")
+	membership.Remove("local")
+	membership.Remove("unknown")
+
+	if _, exists := membership.GetNode("node1"); exists {
+		t.Error("node1 still present after Remove")
+	}
+	if _, ok := membership.LocalNode(); !ok {
+		t.Error("the local node was removed")
+	}
+	if membership.Len() != 1 {
+		t.Errorf("Len = %d, want 1", membership.Len())
+	}
+}
+
+func TestMembership_ConcurrentTransitionsAndReads(t *testing.T) {
+	membership := NewMembership()
+	_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 1})
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func(inc uint64) {
+			defer wg.Done()
+			for range 100 {
+				_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: inc})
+				_ = membership.Suspect("node1")
+				_ = membership.Dead("node1")
+				_ = membership.AllNodes()
+				_ = membership.GetNodesByState(types.StateSuspect)
+				_ = membership.Len()
+			}
+		}(uint64(i))
+	}
+	wg.Wait()
+
+	membership.Remove("node1")
+	if membership.Len() != 0 {
+		t.Errorf("Len = %d, want 0", membership.Len())
+	}
+}
+
+func TestMembership_MergeMetadata(t *testing.T) {
+	membership := NewMembership()
+
+	meta := &types.NodeMetadata{
+		Resources: types.Resources{CPUMillis: 2000},
+		Labels:    map[string]string{"zone": "a"},
+		Priority:  1,
+	}
+	_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 1, Metadata: meta})
+
+	node, _ := membership.GetNode("node1")
+	if node.Metadata.Resources.CPUMillis != 2000 || node.Metadata.Labels["zone"] != "a" {
+		t.Fatalf("metadata = %+v, want the merged metadata", node.Metadata)
+	}
+
+	// The membership keeps its own copy of the labels
+	meta.Labels["zone"] = "mutated"
+	node.Metadata.Labels["zone"] = "mutated"
+	if node, _ := membership.GetNode("node1"); node.Metadata.Labels["zone"] != "a" {
+		t.Errorf("zone = %q, want a", node.Metadata.Labels["zone"])
+	}
+
+	// Same incarnation cannot change metadata
+	_ = membership.Merge(GossipEntry{
+		NodeID: "node1", State: types.StateAlive, Incarnation: 1,
+		Metadata: &types.NodeMetadata{Labels: map[string]string{"zone": "b"}},
+	})
+	if node, _ := membership.GetNode("node1"); node.Metadata.Labels["zone"] != "a" {
+		t.Errorf("zone = %q after same-incarnation merge, want a", node.Metadata.Labels["zone"])
+	}
+
+	// A higher incarnation without metadata keeps what we have
+	_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateSuspect, Incarnation: 2})
+	if node, _ := membership.GetNode("node1"); node.Metadata.Labels["zone"] != "a" {
+		t.Errorf("zone = %q after merge without metadata, want a", node.Metadata.Labels["zone"])
+	}
+
+	// A higher incarnation with metadata replaces it
+	before, _ := membership.GetNode("node1")
+	_ = membership.Merge(GossipEntry{
+		NodeID: "node1", State: types.StateAlive, Incarnation: 3,
+		Metadata: &types.NodeMetadata{Labels: map[string]string{"zone": "c"}, Priority: 5},
+	})
+	node, _ = membership.GetNode("node1")
+	if node.Metadata.Labels["zone"] != "c" || node.Metadata.Priority != 5 {
+		t.Errorf("metadata = %+v, want zone c priority 5", node.Metadata)
+	}
+	if !node.LastUpdated.After(before.LastUpdated) {
+		t.Error("LastUpdated was not refreshed")
+	}
+}
+
+func TestMembership_MergeFillsMissingMetadata(t *testing.T) {
+	membership := NewMembership()
+	zone := func(zone string) *types.NodeMetadata {
+		return &types.NodeMetadata{Labels: map[string]string{"zone": zone}}
+	}
+
+	// First heard of through a suspicion, which carries no metadata
+	_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateSuspect, Incarnation: 2, Suspector: "node2"})
+
+	// A full entry at the same incarnation supersedes nothing but the
+	// missing metadata
+	if !membership.Merge(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 2, Metadata: zone("a"), MetadataIncarnation: 1}) {
+		t.Error("Merge of the missing metadata reported no change")
+	}
+	node, _ := membership.GetNode("node1")
+	if node.State != types.StateSuspect || node.Metadata.Labels["zone"] != "a" || node.MetadataIncarnation != 1 {
+		t.Fatalf("node = %s zone %q published at %d, want suspect with zone a published at 1",
+			node.State, node.Metadata.Labels["zone"], node.MetadataIncarnation)
+	}
+
+	// A newer state relayed with an older copy of the metadata keeps ours
+	_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 3, Metadata: zone("stale"), MetadataIncarnation: 1})
+	if node, _ := membership.GetNode("node1"); node.Incarnation != 3 || node.Metadata.Labels["zone"] != "a" {
+		t.Errorf("node = @%d zone %q, want @3 keeping zone a", node.Incarnation, node.Metadata.Labels["zone"])
+	}
+
+	// Metadata published later replaces it, even from a stale state
+	_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 2, Metadata: zone("b"), MetadataIncarnation: 2})
+	if node, _ := membership.GetNode("node1"); node.Incarnation != 3 || node.Metadata.Labels["zone"] != "b" {
+		t.Errorf("node = @%d zone %q, want @3 with zone b", node.Incarnation, node.Metadata.Labels["zone"])
+	}
+}
+
+func TestMembership_MoveCarriesNewMetadata(t *testing.T) {
+	membership := NewMembership()
+	_ = membership.Merge(GossipEntry{NodeID: "node1", Address: "10.0.0.1:7946", State: types.StateAlive, Incarnation: 1,
+		Metadata: &types.NodeMetadata{Priority: 1}, MetadataIncarnation: 1})
+
+	// The node moved and changed its metadata before announcing itself
+	_ = membership.Merge(GossipEntry{NodeID: "node1", Address: "10.0.0.2:7946", State: types.StateAlive, Incarnation: 2,
+		Metadata: &types.NodeMetadata{Priority: 2}, MetadataIncarnation: 2})
+	node, _ := membership.GetNode("node1")
+	if node.Address != "10.0.0.2:7946" || node.Metadata.Priority != 2 || node.MetadataIncarnation != 2 {
+		t.Fatalf("node = %s priority %d published at %d, want 10.0.0.2:7946 priority 2 published at 2",
+			node.Address, node.Metadata.Priority, node.MetadataIncarnation)
+	}
+
+	// A stale claim from the old address does not take it back
+	if membership.Merge(GossipEntry{NodeID: "node1", Address: "10.0.0.1:7946", State: types.StateAlive, Incarnation: 1,
+		Metadata: &types.NodeMetadata{Priority: 3}, MetadataIncarnation: 3}) {
+		t.Error("a stale claim from another address was applied")
+	}
+	if node, _ := membership.GetNode("node1"); node.Metadata.Priority != 2 {
+		t.Errorf("priority = %d, want 2", node.Metadata.Priority)
+	}
+}
+
+func TestMembership_UpdateLocalMetadata(t *testing.T) {
+	membership := NewMembership(WithLocalNode(types.Node{ID: "local", Incarnation: 1}))
+
+	entry, ok := membership.UpdateLocalMetadata(types.NodeMetadata{Labels: map[string]string{"role": "db"}})
+	if !ok {
+		t.Fatal("UpdateLocalMetadata returned not ok")
+	}
+	if entry.Incarnation != 2 || entry.Metadata == nil || entry.Metadata.Labels["role"] != "db" {
+		t.Errorf("entry = %+v, want incarnation 2 carrying the new metadata", entry)
+	}
+
+	// Peers that hear the entry adopt the new metadata
+	peer := NewMembership()
+	_ = peer.Merge(GossipEntry{NodeID: "local", State: types.StateAlive, Incarnation: 1})
+	if !peer.Merge(entry) {
+		t.Fatal("peer ignored the metadata update")
+	}
+	if node, _ := peer.GetNode("local"); node.Metadata.Labels["role"] != "db" {
+		t.Errorf("peer sees role %q, want db", node.Metadata.Labels["role"])
+	}
+
+	_, _ = membership.LeaveLocal()
+	if _, ok := membership.UpdateLocalMetadata(types.NodeMetadata{}); ok {
+		t.Error("UpdateLocalMetadata succeeded after leaving")
+	}
+}
+
+func TestMembership_EntriesCarryOnlyChangedMetadata(t *testing.T) {
+	meta := types.NodeMetadata{Labels: map[string]string{"zone": "a"}}
+	var refutation GossipEntry
+	membership := NewMembership(
+		WithLocalNode(types.Node{ID: "local", Incarnation: 1, Metadata: meta}),
+		WithRefuteHandler(func(entry GossipEntry) { refutation = entry }))
+
+	// Moving does not change the metadata, so the entry leaves it out
+	if entry, _ := membership.UpdateLocalAddress("10.0.0.2:7946"); entry.Metadata != nil {
+		t.Errorf("address update carries metadata %+v", entry.Metadata)
+	}
+	if entry, _ := membership.UpdateLocalMetadata(meta); entry.Metadata == nil {
+		t.Error("metadata update carries no metadata")
+	}
+
+	membership.Merge(GossipEntry{NodeID: "local", Address: "10.0.0.2:7946", State: types.StateSuspect, Incarnation: 3})
+	if refutation.Incarnation != 4 || refutation.Metadata != nil {
+		t.Errorf("refutation = %+v, want incarnation 4 without metadata", refutation)
+	}
+	if entry, _ := membership.LeaveLocal(); entry.Metadata != nil {
+		t.Errorf("leave carries metadata %+v", entry.Metadata)
+	}
+}
+
+func TestMembership_TombstoneRejectsStaleIncarnations(t *testing.T) {
+	membership := NewMembership()
+	_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 4})
+	_ = membership.Dead("node1")
+
+	stale := []GossipEntry{
+		{NodeID: "node1", State: types.StateAlive, Incarnation: 3},
+		{NodeID: "node1", State: types.StateAlive, Incarnation: 4},
+		{NodeID: "node1", State: types.StateLeft, Incarnation: 4},
+	}
+	for _, entry := range stale {
+		if membership.Merge(entry) {
+			t.Errorf("tombstone accepted %s@%d", entry.State, entry.Incarnation)
+		}
+	}
+	if node, _ := membership.GetNode("node1"); node.State != types.StateDead || node.Incarnation != 4 {
+		t.Errorf("node1 = %s@%d, want dead@4", node.State, node.Incarnation)
+	}
+
+	// The node itself can still come back with a new incarnation
+	if !membership.Merge(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 5}) {
+		t.Error("tombstone rejected a higher incarnation")
+	}
+}
+
+func TestMembership_MergeIgnoresUnknownTombstones(t *testing.T) {
+	membership := NewMembership()
+
+	for _, state := range []types.NodeState{types.StateDead, types.StateLeft} {
+		if membership.Merge(GossipEntry{NodeID: "gone", State: state, Incarnation: 1}) {
+			t.Errorf("Merge of an unknown %s node returned true", state)
+		}
+	}
+	if _, exists := membership.GetNode("gone"); exists {
+		t.Error("an unknown tombstone was added")
+	}
+}
+
+func TestMembership_Reap(t *testing.T) {
+	membership := NewMembership(WithLocalNode(types.Node{ID: "local", Incarnation: 1}))
+	for _, id := range []types.NodeID{"alive", "dead", "left"} {
+		_ = membership.Merge(GossipEntry{NodeID: id, State: types.StateAlive, Incarnation: 1})
+	}
+	_ = membership.Dead("dead")
+	_ = membership.Merge(GossipEntry{NodeID: "left", State: types.StateLeft, Incarnation: 2})
+	_, _ = membership.LeaveLocal()
+
+	if reaped := membership.Reap(time.Hour); reaped != 0 {
+		t.Errorf("reaped %d fresh tombstones, want 0", reaped)
+	}
+
+	sub := membership.Subscribe(4)
+	defer sub.Close()
+
+	time.Sleep(5 * time.Millisecond)
+	if reaped := membership.Reap(time.Millisecond); reaped != 2 {
+		t.Errorf("reaped %d tombstones, want 2", reaped)
+	}
+	for _, id := range []types.NodeID{"dead", "left"} {
+		if _, exists := membership.GetNode(id); exists {
+			t.Errorf("%s still present after Reap", id)
+		}
+	}
+	if _, exists := membership.GetNode("alive"); !exists {
+		t.Error("Reap removed an alive node")
+	}
+	if _, ok := membership.LocalNode(); !ok {
+		t.Error("Reap removed the local node")
+	}
+
+	for range 2 {
+		if event := nextEvent(t, sub); event.Type != EventReap {
+			t.Errorf("event = %s, want reap", event.Type)
+		}
+	}
+
+	// Late gossip about a reaped node does not bring it back
+	_ = membership.Merge(GossipEntry{NodeID: "dead", State: types.StateDead, Incarnation: 1})
+	if _, exists := membership.GetNode("dead"); exists {
+		t.Error("stale gossip resurrected a reaped node")
+	}
+}
+
+func TestMembership_AddressChangeNeedsHigherIncarnation(t *testing.T) {
+	membership := NewMembership()
+	_ = membership.Merge(GossipEntry{NodeID: "node1", Address: "10.0.0.1:7946", State: types.StateAlive, Incarnation: 2})
+
+	if membership.Merge(GossipEntry{NodeID: "node1", Address: "10.0.0.9:7946", State: types.StateAlive, Incarnation: 1}) {
+		t.Error("a stale entry changed the address")
+	}
+
+	if !membership.Merge(GossipEntry{NodeID: "node1", Address: "192.168.1.5:7946", State: types.StateAlive, Incarnation: 3}) {
+		t.Fatal("address change at a higher incarnation was ignored")
+	}
+	if node, _ := membership.GetNode("node1"); node.Address != "192.168.1.5:7946" {
+		t.Errorf("address = %s, want 192.168.1.5:7946", node.Address)
+	}
+
+	// Entries without an address leave it alone
+	_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateSuspect, Incarnation: 4})
+	if node, _ := membership.GetNode("node1"); node.Address != "192.168.1.5:7946" {
+		t.Errorf("address = %q after an entry without one, want 192.168.1.5:7946", node.Address)
+	}
+}
+
+func TestMembership_ConflictKeepExisting(t *testing.T) {
+	membership := NewMembership()
+	_ = membership.Merge(GossipEntry{NodeID: "node1", Address: "10.0.0.1:7946", State: types.StateAlive, Incarnation: 1})
+
+	sub := membership.Subscribe(4)
+	defer sub.Close()
+
+	if membership.Merge(GossipEntry{NodeID: "node1", Address: "10.0.0.2:7946", State: types.StateAlive, Incarnation: 1}) {
+		t.Error("conflicting claim was applied")
+	}
+	if node, _ := membership.GetNode("node1"); node.Address != "10.0.0.1:7946" {
+		t.Errorf("address = %s, want the existing 10.0.0.1:7946", node.Address)
+	}
+
+	event := nextEvent(t, sub)
+	if event.Type != EventConflict || event.Before.Address != "10.0.0.1:7946" || event.After.Address != "10.0.0.2:7946" {
+		t.Errorf("event = %s %s -> %s, want conflict between both addresses", event.Type, event.Before.Address, event.After.Address)
+	}
+	expectNoEvent(t, sub)
+}
+
+func TestMembership_ConflictPreferNew(t *testing.T) {
+	membership := NewMembership(WithConflictPolicy(config.ConflictPreferNew))
+	_ = membership.Merge(GossipEntry{NodeID: "node1", Address: "10.0.0.1:7946", State: types.StateAlive, Incarnation: 1})
+
+	sub := membership.Subscribe(4)
+	defer sub.Close()
+
+	if !membership.Merge(GossipEntry{NodeID: "node1", Address: "10.0.0.2:7946", State: types.StateAlive, Incarnation: 1}) {
+		t.Error("conflicting claim was not applied")
+	}
+	if node, _ := membership.GetNode("node1"); node.Address != "10.0.0.2:7946" {
+		t.Errorf("address = %s, want the new 10.0.0.2:7946", node.Address)
+	}
+
+	if event := nextEvent(t, sub); event.Type != EventConflict {
+		t.Errorf("event = %s, want conflict", event.Type)
+	}
+	if event := nextEvent(t, sub); event.Type != EventUpdate {
+		t.Errorf("event = %s, want update", event.Type)
+	}
+}
+
+func TestMembership_MoveBackIsNotConflict(t *testing.T) {
+	membership := NewMembership()
+	_ = membership.Merge(GossipEntry{NodeID: "node1", Address: "10.0.0.1:7946", State: types.StateAlive, Incarnation: 1})
+	_ = membership.Merge(GossipEntry{NodeID: "node1", Address: "10.0.0.2:7946", State: types.StateAlive, Incarnation: 2})
+
+	sub := membership.Subscribe(4)
+	defer sub.Close()
+
+	// A node that moved away and came back is just moving
+	if !membership.Merge(GossipEntry{NodeID: "node1", Address: "10.0.0.1:7946", State: types.StateAlive, Incarnation: 3}) {
+		t.Fatal("move back was ignored")
+	}
+	if event := nextEvent(t, sub); event.Type != EventUpdate {
+		t.Errorf("event = %s, want update", event.Type)
+	}
+	if node, _ := membership.GetNode("node1"); node.Address != "10.0.0.1:7946" {
+		t.Errorf("address = %s, want 10.0.0.1:7946", node.Address)
+	}
+}
+
+func TestMembership_AlternatingAddressIsConflict(t *testing.T) {
+	// Two nodes refuting each other's claims to one ID look like a node
+	// moving back and forth. Moving back again and again within the window
+	// is the conflict, after which the losing address is ignored until the
+	// window has passed
+	tests := map[string]struct {
+		policy config.ConflictPolicy
+		winner string
+	}{
+		"keep existing": {config.ConflictKeepExisting, "10.0.0.2:7946"},
+		"prefer new":    {config.ConflictPreferNew, "10.0.0.1:7946"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			const window = 100 * time.Millisecond
+			membership := NewMembership(WithConflictPolicy(tt.policy), WithConflictWindow(window))
+			addresses := []string{"10.0.0.1:7946", "10.0.0.2:7946"}
+			incarnation := uint64(1)
+			for ; incarnation <= conflictMoveBacks+1; incarnation++ {
+				if !membership.Merge(GossipEntry{NodeID: "node1", Address: addresses[(incarnation-1)%2], State: types.StateAlive, Incarnation: incarnation}) {
+					t.Fatalf("move at incarnation %d was ignored", incarnation)
+				}
+			}
+
+			sub := membership.Subscribe(4)
+			defer sub.Close()
+
+			_ = membership.Merge(GossipEntry{NodeID: "node1", Address: addresses[(incarnation-1)%2], State: types.StateAlive, Incarnation: incarnation})
+			if event := nextEvent(t, sub); event.Type != EventConflict {
+				t.Fatalf("event = %s, want conflict", event.Type)
+			}
+
+			loser := addresses[0]
+			if tt.winner == loser {
+				loser = addresses[1]
+			}
+			if membership.Merge(GossipEntry{NodeID: "node1", Address: loser, State: types.StateAlive, Incarnation: 9}) {
+				t.Error("a claim from the losing address was applied")
+			}
+			if node, _ := membership.GetNode("node1"); node.Address != tt.winner {
+				t.Errorf("address = %s, want %s", node.Address, tt.winner)
+			}
+
+			// Once the window has passed, a newer claim is a move again
+			time.Sleep(window)
+			if !membership.Merge(GossipEntry{NodeID: "node1", Address: loser, State: types.StateAlive, Incarnation: 10}) {
+				t.Error("a newer claim from the losing address was ignored after the window")
+			}
+			if node, _ := membership.GetNode("node1"); node.Address != loser {
+				t.Errorf("address = %s, want %s", node.Address, loser)
+			}
+		})
+	}
+}
+
+func TestMembership_LocalConflictIsRefuted(t *testing.T) {
+	var refutations []GossipEntry
+	membership := NewMembership(
+		WithLocalNode(types.Node{ID: "local", Address: "10.0.0.1:7946", Incarnation: 1}),
+		WithRefuteHandler(func(entry GossipEntry) { refutations = append(refutations, entry) }),
+		WithConflictPolicy(config.ConflictPreferNew),
+	)
+	sub := membership.Subscribe(4)
+	defer sub.Close()
+
+	// Another node claims our ID with an alive entry at our incarnation,
+	// which could still be our own earlier life
+	_ = membership.Merge(GossipEntry{NodeID: "local", Address: "10.0.0.2:7946", State: types.StateAlive, Incarnation: 1})
+	expectNoEvent(t, sub)
+
+	// It answers our refutation, which only a live node can
+	_ = membership.Merge(GossipEntry{NodeID: "local", Address: "10.0.0.2:7946", State: types.StateAlive, Incarnation: 3})
+	if event := nextEvent(t, sub); event.Type != EventConflict || event.After.Address != "10.0.0.2:7946" {
+		t.Errorf("event = %s from %s, want conflict from 10.0.0.2:7946", event.Type, event.After.Address)
+	}
+
+	if len(refutations) != 2 {
+		t.Fatalf("refutations = %d, want 2", len(refutations))
+	}
+	for i, want := range []uint64{2, 4} {
+		if r := refutations[i]; r.Address != "10.0.0.1:7946" || r.Incarnation != want {
+			t.Errorf("refutation %d = %s@%d, want 10.0.0.1:7946@%d", i, r.Address, r.Incarnation, want)
+		}
+	}
+	if local, _ := membership.LocalNode(); local.Address != "10.0.0.1:7946" {
+		t.Errorf("local address = %s, want 10.0.0.1:7946", local.Address)
+	}
+}
+
+func TestMembership_RumorOfEarlierLifeIsNotConflict(t *testing.T) {
+	tests := map[string]types.NodeState{
+		"dead":  types.StateDead,
+		"left":  types.StateLeft,
+		"alive": types.StateAlive,
+	}
+
+	for name, state := range tests {
+		t.Run(name, func(t *testing.T) {
+			var refutations []GossipEntry
+			membership := NewMembership(
+				WithLocalNode(types.Node{ID: "local", Address: "10.0.0.2:7946", Incarnation: 1}),
+				WithRefuteHandler(func(entry GossipEntry) { refutations = append(refutations, entry) }),
+			)
+			sub := membership.Subscribe(4)
+			defer sub.Close()
+
+			// Restarted on a new address without a snapshot, and hearing
+			// what the members remember of the old one
+			_ = membership.Merge(GossipEntry{NodeID: "local", Address: "10.0.0.1:7946", State: state, Incarnation: 5})
+
+			expectNoEvent(t, sub)
+			if len(refutations) != 1 || refutations[0].Address != "10.0.0.2:7946" || refutations[0].Incarnation != 6 {
+				t.Fatalf("refutations = %+v, want one from 10.0.0.2:7946@6", refutations)
+			}
+
+			// More of the same stale rumor is already refuted
+			_ = membership.Merge(GossipEntry{NodeID: "local", Address: "10.0.0.1:7946", State: state, Incarnation: 5})
+			expectNoEvent(t, sub)
+			if len(refutations) != 1 {
+				t.Errorf("refutations = %d, want 1", len(refutations))
+			}
+		})
+	}
+}
+
+func TestMembership_Restore(t *testing.T) {
+	membership := NewMembership(WithLocalNode(types.Node{ID: "local", Address: "10.0.0.1:7946", Incarnation: 1}))
+
+	membership.Restore([]types.Node{
+		{ID: "local", Address: "10.0.0.1:7946", State: types.StateAlive, Incarnation: 9},
+		{ID: "peer", Address: "10.0.0.2:7946", State: types.StateAlive, Incarnation: 4,
+			Metadata: types.NodeMetadata{Labels: map[string]string{"zone": "a"}}, MetadataIncarnation: 3},
+		{ID: "gone", Address: "10.0.0.3:7946", State: types.StateDead, Incarnation: 2},
+	})
+
+	local, _ := membership.LocalNode()
+	if local.State != types.StateAlive || local.Incarnation != 10 {
+		t.Errorf("local = %s@%d, want alive@10", local.State, local.Incarnation)
+	}
+
+	peer, exists := membership.GetNode("peer")
+	if !exists || peer.Incarnation != 4 || peer.Metadata.Labels["zone"] != "a" || peer.MetadataIncarnation != 3 {
+		t.Errorf("peer = %+v, want it restored as saved", peer)
+	}
+
+	if gone, exists := membership.GetNode("gone"); !exists || gone.State != types.StateDead {
+		t.Errorf("gone = %+v, want it restored dead", gone)
+	}
+}
+
+func TestMembership_RestoredTombstonesRejectStaleGossip(t *testing.T) {
+	tests := map[string]types.NodeState{
+		"dead": types.StateDead,
+		"left": types.StateLeft,
+	}
+
+	for name, state := range tests {
+		t.Run(name, func(t *testing.T) {
+			membership := NewMembership()
+			saved := time.Now().Add(-time.Minute)
+			membership.Restore([]types.Node{
+				{ID: "gone", Address: "10.0.0.3:7946", State: state, Incarnation: 5, LastUpdated: saved},
+			})
+
+			if membership.Merge(GossipEntry{NodeID: "gone", Address: "10.0.0.3:7946", State: types.StateAlive, Incarnation: 5}) {
+				t.Error("stale alive gossip revived a restored tombstone")
+			}
+			if node, _ := membership.GetNode("gone"); node.State != state {
+				t.Errorf("state = %s, want %s", node.State, state)
+			}
+
+			// The saved time counts towards the retention
+			if reaped := membership.Reap(30 * time.Second); reaped != 1 {
+				t.Errorf("reaped = %d, want 1", reaped)
+			}
+		})
+	}
+}
+
+func TestMembership_QueryNodes(t *testing.T) {
+	membership := NewMembership(WithLocalNode(types.Node{
+		ID:       "local",
+		Metadata: types.NodeMetadata{Labels: map[string]string{"role": "server", "hq-only": "true"}},
+	}))
+	labelled := map[types.NodeID]map[string]string{
+		"radio1": {"role": "radio", "team": "alpha"},
+		"radio2": {"role": "radio", "team": "bravo"},
+		"radio3": {"role": "radio", "team": "charlie"},
+		"sensor": {"role": "sensor", "team": "alpha"},
+	}
+	for id, labels := range labelled {
+		_ = membership.Merge(GossipEntry{
+			NodeID: id, State: types.StateAlive, Incarnation: 1,
+			Metadata: &types.NodeMetadata{Labels: labels},
+		})
+	}
+	_ = membership.Merge(GossipEntry{NodeID: "bare", State: types.StateAlive, Incarnation: 1})
+
+	cases := map[string][]types.NodeID{
+		"role=radio,team in (alpha,bravo),!hq-only": {"radio1", "radio2"},
+		"team=alpha":          {"radio1", "sensor"},
+		"hq-only":             {"local"},
+		"!team":               {"local", "bare"},
+		"role!=radio":         {"local", "sensor", "bare"},
+		"team notin (alpha)":  {"local", "radio2", "radio3", "bare"},
+		"role=radio,team=zzz": {},
+		"":                    {"local", "radio1", "radio2", "radio3", "sensor", "bare"},
+	}
+	for s, want := range cases {
+		got := membership.QueryNodes(MustParseSelector(s))
+		if !sameIDs(got, want) {
+			t.Errorf("QueryNodes(%q) = %v, want %v", s, nodeIDs(got), want)
+		}
+	}
+}
+
+func TestMembership_QueryNodesFollowsChanges(t *testing.T) {
+	membership := NewMembership()
+	radios := MustParseSelector("role=radio")
+
+	_ = membership.Merge(GossipEntry{
+		NodeID: "node1", State: types.StateAlive, Incarnation: 1,
+		Metadata: &types.NodeMetadata{Labels: map[string]string{"role": "radio"}},
+	})
+	if got := membership.QueryNodes(radios); !sameIDs(got, []types.NodeID{"node1"}) {
+		t.Fatalf("QueryNodes = %v, want node1", nodeIDs(got))
+	}
+
+	// Relabelled at a higher incarnation
+	_ = membership.Merge(GossipEntry{
+		NodeID: "node1", State: types.StateAlive, Incarnation: 2,
+		Metadata: &types.NodeMetadata{Labels: map[string]string{"role": "sensor"}},
+	})
+	if got := membership.QueryNodes(radios); len(got) != 0 {
+		t.Errorf("QueryNodes = %v after relabel, want none", nodeIDs(got))
+	}
+	if got := membership.QueryNodes(MustParseSelector("role=sensor")); !sameIDs(got, []types.NodeID{"node1"}) {
+		t.Errorf("QueryNodes(role=sensor) = %v, want node1", nodeIDs(got))
+	}
+
+	// The query result is a snapshot
+	got := membership.QueryNodes(MustParseSelector("role=sensor"))
+	got[0].Metadata.Labels["role"] = "radio"
+	if got := membership.QueryNodes(radios); len(got) != 0 {
+		t.Error("mutating a query result changed the index")
+	}
+
+	membership.Remove("node1")
+	if got := membership.QueryNodes(MustParseSelector("role")); len(got) != 0 {
+		t.Errorf("QueryNodes = %v after Remove, want none", nodeIDs(got))
+	}
+}
+
+func nodeIDs(nodes []types.Node) []types.NodeID {
+	ids := make([]types.NodeID, len(nodes))
+	for i, node := range nodes {
+		ids[i] = node.ID
+	}
+	return ids
+}
+
+func sameIDs(nodes []types.Node, want []types.NodeID) bool {
+	if len(nodes) != len(want) {
+		return false
+	}
+	for _, node := range nodes {
+		if !slices.Contains(want, node.ID) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMembership_ChecksumIsOrderIndependent(t *testing.T) {
+	entries := []GossipEntry{
+		{NodeID: "node1", State: types.StateAlive, Incarnation: 1},
+		{NodeID: "node2", State: types.StateSuspect, Incarnation: 4},
+		{NodeID: "node3", State: types.StateAlive, Incarnation: 2},
+	}
+
+	forward, backward := NewMembership(), NewMembership()
+	for i := range entries {
+		_ = forward.Merge(entries[i])
+		_ = backward.Merge(entries[len(entries)-1-i])
+	}
+
+	if forward.Checksum() == 0 || forward.Checksum() != backward.Checksum() {
+		t.Errorf("checksums = %x and %x, want equal and non-zero", forward.Checksum(), backward.Checksum())
+	}
+}
+
+func TestMembership_ChecksumTracksChanges(t *testing.T) {
+	membership := NewMembership()
+	empty := membership.Checksum()
+
+	_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 1})
+	alive := membership.Checksum()
+	if alive == empty {
+		t.Fatal("checksum unchanged by a join")
+	}
+
+	_ = membership.Suspect("node1")
+	suspect := membership.Checksum()
+	if suspect == alive {
+		t.Error("checksum unchanged by a state change")
+	}
+
+	_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateSuspect, Incarnation: 2})
+	if membership.Checksum() == suspect {
+		t.Error("checksum unchanged by an incarnation change")
+	}
+
+	// Tombstones do not count, so a dead member looks like a reaped one
+	_ = membership.Dead("node1")
+	if membership.Checksum() != empty {
+		t.Errorf("checksum = %x with only a tombstone, want %x", membership.Checksum(), empty)
+	}
+	membership.Remove("node1")
+	if membership.Checksum() != empty {
+		t.Errorf("checksum = %x after Remove, want %x", membership.Checksum(), empty)
+	}
+}
+
+func TestMembership_ChecksumMatchesRecomputed(t *testing.T) {
+	membership := NewMembership(
+		WithLocalNode(types.Node{ID: "local", Incarnation: 1}),
+		WithRefuteHandler(func(GossipEntry) {}),
+	)
+	_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 1})
+	_ = membership.Merge(GossipEntry{NodeID: "node2", State: types.StateAlive, Incarnation: 1})
+	_ = membership.Merge(GossipEntry{NodeID: "node3", State: types.StateAlive, Incarnation: 5})
+	_ = membership.Suspect("node2")
+	_ = membership.Dead("node3")
+	_ = membership.Merge(GossipEntry{NodeID: "node3", State: types.StateAlive, Incarnation: 6})
+	_ = membership.Merge(GossipEntry{NodeID: "local", State: types.StateSuspect, Incarnation: 1})
+	_, _ = membership.UpdateLocalMetadata(types.NodeMetadata{Priority: 1})
+	membership.Remove("node1")
+
+	var want uint64
+	for _, node := range membership.AllNodes() {
+		want ^= memberHash(&node)
+	}
+	if got := membership.Checksum(); got != want {
+		t.Errorf("incremental checksum = %x, recomputed = %x", got, want)
+	}
+}
+
+func TestMembership_GetNodeByAddressPrefersLive(t *testing.T) {
+	membership := NewMembership()
+	membership.Merge(GossipEntry{NodeID: "old", Address: "10.0.0.1:1234", State: types.StateAlive, Incarnation: 1})
+	membership.Dead("old")
+	membership.Merge(GossipEntry{NodeID: "new", Address: "10.0.0.1:1234", State: types.StateAlive, Incarnation: 1})
+
+	node, exists := membership.GetNodeByAddress("10.0.0.1:1234")
+	if !exists || node.ID != "new" {
+		t.Errorf("GetNodeByAddress = %s, %v, want new", node.ID, exists)
+	}
+	if _, exists := membership.GetNodeByAddress("10.0.0.2:1234"); exists {
+		t.Error("GetNodeByAddress found a node at an unknown address")
+	}
+}
+
+func TestMembership_GetNodeByAddressFollowsMoves(t *testing.T) {
+	membership := NewMembership()
+	membership.Merge(GossipEntry{NodeID: "node1", Address: "10.0.0.1:1234", State: types.StateAlive, Incarnation: 1})
+	membership.Merge(GossipEntry{NodeID: "node1", Address: "10.0.0.9:1234", State: types.StateAlive, Incarnation: 2})
+
+	if _, exists := membership.GetNodeByAddress("10.0.0.1:1234"); exists {
+		t.Error("GetNodeByAddress found a node at the address it moved away from")
+	}
+	if node, exists := membership.GetNodeByAddress("10.0.0.9:1234"); !exists || node.ID != "node1" {
+		t.Errorf("GetNodeByAddress = %s, %v, want node1", node.ID, exists)
+	}
+
+	membership.Remove("node1")
+	if _, exists := membership.GetNodeByAddress("10.0.0.9:1234"); exists {
+		t.Error("GetNodeByAddress found a removed node")
+	}
+}
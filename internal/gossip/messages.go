@@ -13,6 +13,8 @@ const (
 	MessageTypeSync
 	MessageTypeSyncResponse
 	MessageTypeLeave
+	MessageTypeCompound
+	MessageTypeGossip
 )
 
 // String returns a human-readable representation of MessageType
@@ -32,6 +34,10 @@ func (m MessageType) String() string {
 		return "sync-response"
 	case MessageTypeLeave:
 		return "leave"
+	case MessageTypeCompound:
+		return "compound"
+	case MessageTypeGossip:
+		return "gossip"
 	default:
 		return "unknown"
 	}
@@ -43,6 +49,23 @@ type MessageHeader struct {
 	Type     MessageType
 	SeqNo    uint32 // For correlating requests with responses
 	SourceID string // NodeID of the sender
+
+	// Checksum is the sender's membership checksum, carried on Ping and
+	// Ack. Zero when not sent
+	Checksum uint64
+
+	ClusterID string // Cluster the sender belongs to, empty from older peers
+	Team      string // Team the sender belongs to
+}
+
+// messageHeader gives access to the header of any message embedding it
+func (h *MessageHeader) messageHeader() *MessageHeader {
+	return h
+}
+
+// headed is implemented by every message that embeds MessageHeader
+type headed interface {
+	messageHeader() *MessageHeader
 }
 
 // Ping checks if a target node is alive
@@ -63,6 +86,40 @@ type Nack struct {
 	MessageHeader
 }
 
+// Leave announces that the sender is leaving the cluster on purpose
+type Leave struct {
+	MessageHeader
+	Incarnation uint64 // Incarnation the sender left at
+}
+
+// Sync pushes the sender's full membership state and asks for the
+// receiver's in return (push-pull anti-entropy)
+type Sync struct {
+	MessageHeader
+	Nodes []GossipEntry
+}
+
+// SyncResponse answers a Sync with the receiver's full membership state
+type SyncResponse struct {
+	MessageHeader
+	Nodes []GossipEntry
+}
+
+// Compound carries several encoded messages to the same peer in one
+// datagram. It is only sent to peers that set NodeMetadata.Compound
+type Compound struct {
+	MessageHeader
+	Messages [][]byte // each one encoded on its own, never a Compound
+}
+
+// Gossip carries queued broadcasts to a peer picked by the selection
+// strategy, with no reply expected. It is only sent to peers that set
+// NodeMetadata.Gossip
+type Gossip struct {
+	MessageHeader
+	Gossip []GossipEntry
+}
+
 // PingReq asks another node to ping a target on our behalf (indirect ping)
 type PingReq struct {
 	Ping
@@ -77,4 +134,10 @@ type GossipEntry struct {
 	Incarnation uint64
 	Metadata    *types.NodeMetadata // nil if metadata unchanged
 	Timestamp   int64               // Unix nanos
+	Suspector   types.NodeID        // node that raised the suspicion, for suspect entries
+	ClusterID   string              // cluster the member belongs to, empty if unknown
+
+	// MetadataIncarnation is the incarnation Metadata was published at,
+	// which may be older than Incarnation. Zero means Incarnation
+	MetadataIncarnation uint64
 }
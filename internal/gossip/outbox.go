@@ -0,0 +1,97 @@
+package gossip
+
+import (
+	"context"
+	"time"
+)
+
+// outboxSize bounds the messages waiting for sendLoop
+const outboxSize = 256
+
+// outgoing is an encoded message waiting to be sent
+type outgoing struct {
+	addr    string
+	version uint8
+	data    []byte
+}
+
+// sendLoop sends queued messages, packing those bound for the same peer
+// into compound messages. Each message waits at most BatchDelay for others
+// to join it
+func (g *gossiper) sendLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			g.flush(g.drain(nil))
+			return
+		case first := <-g.outbox:
+			pending := []outgoing{first}
+			if delay := g.cfg.Gossip.BatchDelay; delay > 0 {
+				pending = g.collect(ctx, pending, delay)
+			}
+			g.flush(g.drain(pending))
+		}
+	}
+}
+
+// collect adds queued messages to pending until delay has passed
+func (g *gossiper) collect(ctx context.Context, pending []outgoing, delay time.Duration) []outgoing {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return pending
+		case <-timer.C:
+			return pending
+		case out := <-g.outbox:
+			pending = append(pending, out)
+		}
+	}
+}
+
+// drain adds every message already queued to pending
+func (g *gossiper) drain(pending []outgoing) []outgoing {
+	for {
+		select {
+		case out := <-g.outbox:
+			pending = append(pending, out)
+		default:
+			return pending
+		}
+	}
+}
+
+// flush sends pending, one peer at a time in the order they were first
+// queued for
+func (g *gossiper) flush(pending []outgoing) {
+	var addrs []string
+	byAddr := make(map[string][]outgoing)
+	for _, out := range pending {
+		if _, seen := byAddr[out.addr]; !seen {
+			addrs = append(addrs, out.addr)
+		}
+		byAddr[out.addr] = append(byAddr[out.addr], out)
+	}
+
+	for _, addr := range addrs {
+		queued := byAddr[addr]
+		parts := make([][]byte, len(queued))
+		for i, out := range queued {
+			parts[i] = out.data
+		}
+
+		compound := &Compound{MessageHeader: g.detector.header(MessageTypeCompound, 0)}
+		g.stamp(compound)
+		setVersion(compound, queued[0].version)
+
+		datagrams, err := packMessages(g.codecFor(addr), compound.MessageHeader, parts, g.cfg.Gossip.MaxPacketSize)
+		if err != nil {
+			datagrams = parts // send them one by one rather than not at all
+		}
+		for _, data := range datagrams {
+			_ = g.transport.SendTo(addr, data)
+		}
+	}
+}
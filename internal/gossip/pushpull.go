@@ -0,0 +1,159 @@
+package gossip
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+var ErrUnexpectedMessage = errors.New("unexpected message type")
+
+// pushPullLoop exchanges full state with a live member every
+// PushPullInterval, so views repair themselves after long partitions
+func (g *gossiper) pushPullLoop(ctx context.Context) {
+	interval := g.cfg.Gossip.PushPullInterval
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			peers := selectPeers(g.members, g.selection, 1, types.NodeID(g.cfg.Node.ID))
+			if len(peers) == 0 {
+				continue
+			}
+			_ = g.pushPull(peers[0].Address)
+		}
+	}
+}
+
+// pushPull sends our full state to addr and merges the state it answers with
+func (g *gossiper) pushPull(addr string) error {
+	syncMsg := &Sync{
+		MessageHeader: g.detector.header(MessageTypeSync, g.detector.nextSeqNo()),
+		Nodes:         g.localState(),
+	}
+	g.stamp(syncMsg)
+	setVersion(syncMsg, g.versionFor(addr))
+
+	data, err := g.codecFor(addr).Encode(syncMsg)
+	if err != nil {
+		return err
+	}
+
+	raw, err := g.stream.Request(addr, data)
+	if err != nil {
+		return err
+	}
+
+	msg, err := g.codec.Decode(raw)
+	if err != nil {
+		return err
+	}
+	resp, ok := msg.(*SyncResponse)
+	if !ok {
+		return ErrUnexpectedMessage
+	}
+	if !g.admits(&resp.MessageHeader) {
+		return ErrForeignCluster
+	}
+
+	g.mergeGossip(resp.Nodes)
+	g.clusterMerged(&resp.MessageHeader)
+	return nil
+}
+
+// pushState sends addr as much of our state as fits in a datagram, for
+// when there is no stream transport to push-pull over. The entries are
+// shuffled so that repeated pushes to a large cluster cover all of it
+func (g *gossiper) pushState(addr string) error {
+	peer, known := g.members.GetNodeByAddress(addr)
+	codec := g.codecWith(peer, known)
+
+	msg := &Gossip{MessageHeader: g.detector.header(MessageTypeGossip, 0)}
+	g.stamp(msg)
+	setVersion(msg, g.versionWith(peer))
+
+	entries := g.localState()
+	// #nosec G404 -- which entries are pushed first does not need a secure source
+	rand.Shuffle(len(entries), func(i, j int) { entries[i], entries[j] = entries[j], entries[i] })
+	fits := fitsPacket(codec, g.cfg.Gossip.MaxPacketSize, func(entries []GossipEntry) any {
+		c := *msg
+		c.Gossip = entries
+		return &c
+	})
+	msg.Gossip = entries[:sort.Search(len(entries), func(n int) bool { return !fits(entries[:n+1]) })]
+
+	data, err := codec.Encode(msg)
+	if err != nil {
+		return err
+	}
+	return g.transport.SendTo(addr, data)
+}
+
+// streamLoop answers push-pull requests from peers
+func (g *gossiper) streamLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case req, ok := <-g.stream.Requests():
+			if !ok {
+				return
+			}
+			req.Respond(g.handleSync(req.Payload))
+		}
+	}
+}
+
+// handleSync merges a peer's full state and returns ours, or nil if the
+// request cannot be answered
+func (g *gossiper) handleSync(payload []byte) []byte {
+	msg, err := g.codec.Decode(payload)
+	if err != nil {
+		return nil
+	}
+	syncMsg, ok := msg.(*Sync)
+	if !ok || !g.admits(&syncMsg.MessageHeader) {
+		return nil
+	}
+
+	g.mergeGossip(syncMsg.Nodes)
+
+	resp := &SyncResponse{
+		MessageHeader: g.detector.header(MessageTypeSyncResponse, syncMsg.SeqNo),
+		Nodes:         g.localState(),
+	}
+	g.stamp(resp)
+	setVersion(resp, syncMsg.Version) // answer in the version we were asked in
+
+	peer, known := g.members.GetNode(types.NodeID(syncMsg.SourceID))
+	codec := peerCodec{Codec: g.codec, compress: known && g.compressFor(peer)}
+	data, err := codec.Encode(resp)
+	if err != nil {
+		return nil
+	}
+	g.clusterMerged(&syncMsg.MessageHeader)
+	return data
+}
+
+// localState returns an entry for every member we know about, including
+// dead and left ones, so the peer can apply them all through Merge
+func (g *gossiper) localState() []GossipEntry {
+	nodes := g.members.AllNodes()
+	entries := make([]GossipEntry, 0, len(nodes))
+	for _, node := range nodes {
+		entries = append(entries, fullEntryFromNode(node))
+	}
+	return entries
+}
@@ -0,0 +1,180 @@
+package gossip
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/michael-martinez-dev/adaptive-hive/internal/config"
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+// --- Helpers --- //
+
+func newStreamGossiper(t *testing.T, network *TestNetwork, cfg config.Config) Gossiper {
+	t.Helper()
+	id := cfg.Node.ID
+	return newTestGossiperWithConfig(t, cfg, network.NewTransport(id), NewCodec(), WithStreamTransport(network.NewStreamTransport(id)))
+}
+
+func TestPushPull_JoinCatchesUpInOneRoundTrip(t *testing.T) {
+	network := NewTestNetwork()
+	g1 := newStreamGossiper(t, network, testGossiperConfig("node1"))
+	g2 := newStreamGossiper(t, network, testGossiperConfig("node2"))
+	g3 := newStreamGossiper(t, network, testGossiperConfig("node3"))
+
+	if err := g2.Join("node1"); err != nil {
+		t.Fatalf("node2 failed to join: %v", err)
+	}
+	if err := g3.Join("node1"); err != nil {
+		t.Fatalf("node3 failed to join: %v", err)
+	}
+
+	// No waiting: the Sync exchange itself carries the full member list
+	if !knowsAlive(g3, "node1", "node2", "node3") {
+		t.Error("node3 did not learn the full membership from its join")
+	}
+	if !knowsAlive(g1, "node1", "node2", "node3") {
+		t.Error("node1 did not learn both joiners")
+	}
+}
+
+func TestPushPull_JoinFallsBackToPing(t *testing.T) {
+	network := NewTestNetwork()
+	newTestGossiper(t, network, "node1") // no stream transport
+	g2 := newStreamGossiper(t, network, testGossiperConfig("node2"))
+
+	if err := g2.Join("node1"); err != nil {
+		t.Fatalf("node2 failed to join: %v", err)
+	}
+
+	waitFor(t, 3*time.Second, func() bool { return knowsAlive(g2, "node1") }, "node2 to learn node1")
+}
+
+func TestPushPull_PeriodicSyncRepairsViews(t *testing.T) {
+	network := NewTestNetwork()
+
+	cfg1 := testGossiperConfig("node1")
+	cfg1.Gossip.PushPullInterval = 100 * time.Millisecond
+	cfg2 := testGossiperConfig("node2")
+	cfg2.Gossip.PushPullInterval = 100 * time.Millisecond
+
+	g1 := newStreamGossiper(t, network, cfg1)
+	g2 := newStreamGossiper(t, network, cfg2)
+
+	if err := g2.Join("node1"); err != nil {
+		t.Fatalf("node2 failed to join: %v", err)
+	}
+
+	// Both know node9, but only node1 hears that it left. Merged directly,
+	// so it is never queued for piggybacked gossip and can only reach node2
+	// through push-pull
+	for _, g := range []Gossiper{g1, g2} {
+		_ = g.Members().Merge(GossipEntry{NodeID: "node9", Address: "node9", State: types.StateAlive, Incarnation: 2})
+	}
+	_ = g1.Members().Merge(GossipEntry{NodeID: "node9", Address: "node9", State: types.StateLeft, Incarnation: 3})
+
+	waitFor(t, 3*time.Second, func() bool {
+		node, exists := g2.Members().GetNode("node9")
+		return exists && node.State == types.StateLeft && node.Incarnation == 3
+	}, "node2 to learn node9 through push-pull")
+}
+
+func TestPushPull_RejectsMalformedRequest(t *testing.T) {
+	network := NewTestNetwork()
+	newStreamGossiper(t, network, testGossiperConfig("node1"))
+
+	client := network.NewStreamTransport("client")
+	if err := client.Start(context.Background()); err != nil {
+		t.Fatal("could not start client")
+	}
+
+	if _, err := client.Request("node1", []byte("garbage")); err != ErrNoResponse {
+		t.Errorf("err = %v, want ErrNoResponse", err)
+	}
+}
+
+func TestPushPull_ChecksumMismatchTriggersSync(t *testing.T) {
+	network := NewTestNetwork()
+
+	var gossipers []Gossiper
+	for _, id := range []string{"node1", "node2"} {
+		cfg := testGossiperConfig(id)
+		cfg.Gossip.PushPullInterval = 0 // only checksum mismatches can sync
+		cfg.Gossip.ChecksumSyncInterval = 50 * time.Millisecond
+		gossipers = append(gossipers, newStreamGossiper(t, network, cfg))
+	}
+	g1, g2 := gossipers[0], gossipers[1]
+
+	if err := g2.Join("node1"); err != nil {
+		t.Fatalf("node2 failed to join: %v", err)
+	}
+	for _, g := range gossipers {
+		_ = g.Members().Merge(GossipEntry{NodeID: "node9", Address: "node9", State: types.StateAlive, Incarnation: 2})
+	}
+
+	// Merged directly, so only a sync can carry it to node2
+	_ = g1.Members().Merge(GossipEntry{NodeID: "node9", Address: "node9", State: types.StateLeft, Incarnation: 3})
+
+	waitFor(t, 3*time.Second, func() bool {
+		node, _ := g2.Members().GetNode("node9")
+		return node.State == types.StateLeft && node.Incarnation == 3
+	}, "node2 to sync after a checksum mismatch")
+}
+
+func TestPushPull_ChecksumMismatchPushesStateWithoutStreams(t *testing.T) {
+	network := NewTestNetwork()
+
+	var gossipers []Gossiper
+	for _, id := range []string{"node1", "node2"} {
+		cfg := testGossiperConfig(id)
+		cfg.Gossip.ChecksumSyncInterval = 50 * time.Millisecond
+		gossipers = append(gossipers, newTestGossiperWithConfig(t, cfg, network.NewTransport(id), NewCodec()))
+	}
+	g1, g2 := gossipers[0], gossipers[1]
+
+	if err := g2.Join("node1"); err != nil {
+		t.Fatalf("node2 failed to join: %v", err)
+	}
+	for _, g := range gossipers {
+		_ = g.Members().Merge(GossipEntry{NodeID: "node9", Address: "node9", State: types.StateAlive, Incarnation: 2})
+	}
+
+	// Merged directly and with no stream to sync over, so only a pushed
+	// datagram can carry it to node2
+	_ = g1.Members().Merge(GossipEntry{NodeID: "node9", Address: "node9", State: types.StateLeft, Incarnation: 3})
+
+	waitFor(t, 3*time.Second, func() bool {
+		node, _ := g2.Members().GetNode("node9")
+		return node.State == types.StateLeft && node.Incarnation == 3
+	}, "node1 to push its state after a checksum mismatch")
+}
+
+func TestPushPull_FillsMetadataOfMemberLearnedThroughSuspicion(t *testing.T) {
+	network := NewTestNetwork()
+	g1 := newStreamGossiper(t, network, testGossiperConfig("node1"))
+	g2 := newStreamGossiper(t, network, testGossiperConfig("node2")).(*gossiper)
+
+	meta := types.NodeMetadata{
+		Labels:      map[string]string{"zone": "a"},
+		Protocol:    types.ProtocolRange{Min: ProtocolV2, Max: ProtocolV2},
+		Compression: true,
+		Compound:    true,
+	}
+	_ = g1.Members().Merge(GossipEntry{NodeID: "node9", Address: "node9", State: types.StateAlive, Incarnation: 2, Metadata: &meta})
+
+	// node2 first hears of node9 through a suspicion at the same incarnation
+	g2.mergeGossip([]GossipEntry{{NodeID: "node9", Address: "node9", State: types.StateSuspect, Incarnation: 2, Suspector: "node3"}})
+
+	if err := g2.pushPull("node1"); err != nil {
+		t.Fatalf("pushPull failed: %v", err)
+	}
+
+	node, _ := g2.Members().GetNode("node9")
+	if node.Metadata.Labels["zone"] != "a" || node.Metadata.Protocol != meta.Protocol || !node.Metadata.Compression || !node.Metadata.Compound {
+		t.Errorf("metadata = %+v, want node9's metadata from the push-pull", node.Metadata)
+	}
+	if got := g2.Members().QueryNodes(MustParseSelector("zone=a")); len(got) != 1 {
+		t.Errorf("QueryNodes(zone=a) = %d nodes, want node9", len(got))
+	}
+}
@@ -0,0 +1,125 @@
+package gossip
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+// ProbeScheduler hands out probe targets in shuffled round-robin order, as
+// described in the SWIM paper. Every live member is probed once per round,
+// which bounds the worst-case time before a failed member is probed
+type ProbeScheduler interface {
+	// Next returns the next member to probe, or false if there is none
+	Next() (types.Node, bool)
+}
+
+type probeScheduler struct {
+	members  Membership
+	local    types.NodeID
+	strategy SelectionStrategy
+
+	mu    sync.Mutex
+	order []types.NodeID
+	index int
+	known map[types.NodeID]bool // every member placed in the current round
+}
+
+// NewProbeScheduler creates a ProbeScheduler over the live members of
+// members, never returning local. Each round is ordered by strategy, or
+// shuffled uniformly if strategy is nil
+func NewProbeScheduler(members Membership, local types.NodeID, strategy SelectionStrategy) ProbeScheduler {
+	if strategy == nil {
+		strategy = NewUniformSelection()
+	}
+	return &probeScheduler{
+		members:  members,
+		local:    local,
+		strategy: strategy,
+		known:    make(map[types.NodeID]bool),
+	}
+}
+
+func (s *probeScheduler) Next() (types.Node, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	live := make(map[types.NodeID]types.Node)
+	for _, node := range s.members.RandomNodes(s.members.Len(), s.local) {
+		live[node.ID] = node
+	}
+	if len(live) == 0 {
+		return types.Node{}, false
+	}
+
+	if s.index >= len(s.order) {
+		s.reshuffle(live)
+	}
+	s.insertJoined(live)
+
+	for {
+		if s.index >= len(s.order) {
+			s.reshuffle(live)
+		}
+
+		id := s.order[s.index]
+		s.index++
+
+		// Members that died or left since the round started are skipped
+		if node, ok := live[id]; ok {
+			return node, true
+		}
+	}
+}
+
+// insertJoined places members that joined mid-round at random positions
+// among the members not yet probed this round
+func (s *probeScheduler) insertJoined(live map[types.NodeID]types.Node) {
+	for id := range live {
+		if s.known[id] {
+			continue
+		}
+		s.known[id] = true
+
+		// #nosec G404 -- probe order does not need a secure source
+		pos := s.index + rand.Intn(len(s.order)-s.index+1)
+		s.order = append(s.order, "")
+		copy(s.order[pos+1:], s.order[pos:])
+		s.order[pos] = id
+	}
+}
+
+// reshuffle starts a new round over the current live members, in the order
+// the strategy picks them. A strategy may return fewer members than asked
+// for, so the ones it left out follow in random order: every live member is
+// still probed once per round
+func (s *probeScheduler) reshuffle(live map[types.NodeID]types.Node) {
+	candidates := make([]types.Node, 0, len(live))
+	for _, node := range live {
+		candidates = append(candidates, node)
+	}
+	localNode, _ := s.members.LocalNode()
+
+	s.order = s.order[:0]
+	s.known = make(map[types.NodeID]bool, len(live))
+	for _, node := range s.strategy.Select(localNode, candidates, len(candidates)) {
+		if _, ok := live[node.ID]; ok && !s.known[node.ID] {
+			s.order = append(s.order, node.ID)
+			s.known[node.ID] = true
+		}
+	}
+
+	rest := len(s.order)
+	for id := range live {
+		if !s.known[id] {
+			s.order = append(s.order, id)
+			s.known[id] = true
+		}
+	}
+	// #nosec G404 -- probe order does not need a secure source
+	rand.Shuffle(len(s.order)-rest, func(i, j int) {
+		s.order[rest+i], s.order[rest+j] = s.order[rest+j], s.order[rest+i]
+	})
+	s.index = 0
+}
@@ -0,0 +1,151 @@
+package gossip
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+func TestProbeScheduler_ProbesEachMemberOncePerRound(t *testing.T) {
+	membership := NewMembership()
+	for i := range 5 {
+		_ = membership.Merge(GossipEntry{NodeID: types.NodeID(fmt.Sprintf("node%d", i)), State: types.StateAlive, Incarnation: 1})
+	}
+
+	scheduler := NewProbeScheduler(membership, "node0", nil)
+
+	for round := range 3 {
+		seen := make(map[types.NodeID]int)
+		for range 4 {
+			node, ok := scheduler.Next()
+			if !ok {
+				t.Fatal("Next returned not ok")
+			}
+			seen[node.ID]++
+		}
+
+		if len(seen) != 4 {
+			t.Errorf("round %d probed %d distinct nodes, want 4", round, len(seen))
+		}
+		if seen["node0"] != 0 {
+			t.Errorf("round %d probed the local node", round)
+		}
+	}
+}
+
+func TestProbeScheduler_JoinedMemberProbedInCurrentRound(t *testing.T) {
+	membership := NewMembership()
+	_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 1})
+	_ = membership.Merge(GossipEntry{NodeID: "node2", State: types.StateAlive, Incarnation: 1})
+	_ = membership.Merge(GossipEntry{NodeID: "node3", State: types.StateAlive, Incarnation: 1})
+
+	scheduler := NewProbeScheduler(membership, "local", nil)
+
+	first, _ := scheduler.Next()
+
+	_ = membership.Merge(GossipEntry{NodeID: "joined", State: types.StateAlive, Incarnation: 1})
+
+	seen := map[types.NodeID]bool{first.ID: true}
+	for range 3 {
+		node, ok := scheduler.Next()
+		if !ok {
+			t.Fatal("Next returned not ok")
+		}
+		if seen[node.ID] {
+			t.Fatalf("%s probed twice before the round ended", node.ID)
+		}
+		seen[node.ID] = true
+	}
+
+	if !seen["joined"] {
+		t.Error("joined node was not probed in the current round")
+	}
+}
+
+func TestProbeScheduler_SkipsDeadAndLeft(t *testing.T) {
+	membership := NewMembership()
+	mergeEveryState(t, membership)
+
+	scheduler := NewProbeScheduler(membership, "local", nil)
+
+	for range 100 {
+		node, ok := scheduler.Next()
+		if !ok {
+			t.Fatal("Next returned not ok")
+		}
+		if node.State == types.StateDead || node.State == types.StateLeft {
+			t.Fatalf("Next unexpectedly returned %s node", node.State)
+		}
+	}
+}
+
+func TestProbeScheduler_SkipsMemberThatDiedMidRound(t *testing.T) {
+	membership := NewMembership()
+	_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 1})
+	_ = membership.Merge(GossipEntry{NodeID: "node2", State: types.StateAlive, Incarnation: 1})
+
+	scheduler := NewProbeScheduler(membership, "local", nil)
+
+	first, _ := scheduler.Next()
+	other := types.NodeID("node1")
+	if first.ID == other {
+		other = "node2"
+	}
+
+	_ = membership.Merge(GossipEntry{NodeID: other, State: types.StateDead, Incarnation: 1})
+
+	for range 10 {
+		node, ok := scheduler.Next()
+		if !ok {
+			t.Fatal("Next returned not ok")
+		}
+		if node.ID == other {
+			t.Fatal("Next returned a node that died mid-round")
+		}
+	}
+}
+
+func TestProbeScheduler_EmptyMembership(t *testing.T) {
+	scheduler := NewProbeScheduler(NewMembership(), "local", nil)
+
+	if _, ok := scheduler.Next(); ok {
+		t.Error("Next returned ok for an empty membership")
+	}
+}
+
+// noSelection is a strategy that never picks anything
+type noSelection struct{}
+
+func (noSelection) Select(types.Node, []types.Node, int) []types.Node { return nil }
+
+func TestProbeScheduler_ProbesMembersTheStrategyLeftOut(t *testing.T) {
+	membership := NewMembership()
+	_ = membership.Merge(GossipEntry{NodeID: "node1", State: types.StateAlive, Incarnation: 1})
+	_ = membership.Merge(GossipEntry{NodeID: "node2", State: types.StateAlive, Incarnation: 1})
+
+	scheduler := NewProbeScheduler(membership, "local", noSelection{})
+
+	first, ok := scheduler.Next()
+	if !ok {
+		t.Fatal("Next returned not ok")
+	}
+	other := types.NodeID("node1")
+	if first.ID == other {
+		other = "node2"
+	}
+
+	// The rest of the round is dead, so the next round has to be built
+	// without any help from the strategy
+	_ = membership.Merge(GossipEntry{NodeID: other, State: types.StateDead, Incarnation: 1})
+
+	for range 10 {
+		node, ok := scheduler.Next()
+		if !ok {
+			t.Fatal("Next returned not ok")
+		}
+		if node.ID != first.ID {
+			t.Fatalf("Next returned %s, want %s", node.ID, first.ID)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package gossip
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/michael-martinez-dev/adaptive-hive/internal/config"
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+// SelectionStrategy decides which members gossip is sent to and which are
+// used as indirect-ping helpers and push-pull partners. It also orders each
+// probe round, though every member is still probed once per round
+type SelectionStrategy interface {
+	// Select returns up to k of candidates, in the order they should be
+	// used. local is the selecting node, a zero value if it is unknown
+	// candidates must not be modified
+	Select(local types.Node, candidates []types.Node, k int) []types.Node
+}
+
+type uniformSelection struct{}
+
+// NewUniformSelection picks members uniformly at random
+func NewUniformSelection() SelectionStrategy {
+	return uniformSelection{}
+}
+
+func (uniformSelection) Select(_ types.Node, candidates []types.Node, k int) []types.Node {
+	selected := make([]types.Node, len(candidates))
+	copy(selected, candidates)
+	// #nosec G404 -- peer selection does not need a secure source
+	rand.Shuffle(len(selected), func(i, j int) { selected[i], selected[j] = selected[j], selected[i] })
+	return selected[:min(max(k, 0), len(selected))]
+}
+
+type prioritySelection struct{}
+
+// NewPrioritySelection picks members at random, weighted by
+// NodeMetadata.Priority: a member is picked Priority+1 times as often as a
+// member with priority zero. Negative priorities count as zero
+func NewPrioritySelection() SelectionStrategy {
+	return prioritySelection{}
+}
+
+func (prioritySelection) Select(_ types.Node, candidates []types.Node, k int) []types.Node {
+	// Weighted sampling without replacement (Efraimidis-Spirakis): each
+	// member draws -ln(u)/w and the smallest draws win
+	type draw struct {
+		node types.Node
+		key  float64
+	}
+	draws := make([]draw, len(candidates))
+	for i, node := range candidates {
+		weight := float64(max(node.Metadata.Priority, 0) + 1)
+		// #nosec G404 -- peer selection does not need a secure source
+		draws[i] = draw{node: node, key: -math.Log(1-rand.Float64()) / weight}
+	}
+	sort.Slice(draws, func(i, j int) bool { return draws[i].key < draws[j].key })
+
+	selected := make([]types.Node, 0, min(max(k, 0), len(draws)))
+	for _, d := range draws[:cap(selected)] {
+		selected = append(selected, d.node)
+	}
+	return selected
+}
+
+type localitySelection struct {
+	label          string
+	remoteFraction float64
+	base           SelectionStrategy
+}
+
+// NewLocalitySelection prefers members whose label matches the local
+// node's, reserving remoteFraction of the selections for members in other
+// localities so that information still crosses between them. Within each
+// locality, members are picked by base, or uniformly if base is nil
+// When the local node has no such label, every member counts as local
+func NewLocalitySelection(label string, remoteFraction float64, base SelectionStrategy) SelectionStrategy {
+	if base == nil {
+		base = NewUniformSelection()
+	}
+	return &localitySelection{
+		label:          label,
+		remoteFraction: min(max(remoteFraction, 0), 1),
+		base:           base,
+	}
+}
+
+func (s *localitySelection) Select(local types.Node, candidates []types.Node, k int) []types.Node {
+	locality, ok := local.Metadata.Labels[s.label]
+	if !ok {
+		return s.base.Select(local, candidates, k)
+	}
+
+	var near, remote []types.Node
+	for _, node := range candidates {
+		if node.Metadata.Labels[s.label] == locality {
+			near = append(near, node)
+		} else {
+			remote = append(remote, node)
+		}
+	}
+
+	// Nearby members come first. Remote ones take their reserved slots and
+	// any that are left for lack of nearby members
+	k = min(max(k, 0), len(candidates))
+	selected := s.base.Select(local, near, k-min(s.remoteSlots(k), len(remote)))
+	return append(selected, s.base.Select(local, remote, k-len(selected))...)
+}
+
+// remoteSlots returns how many of k selections go to remote localities,
+// rounding randomly so that remoteFraction holds on average even when k is
+// small
+func (s *localitySelection) remoteSlots(k int) int {
+	exact := float64(k) * s.remoteFraction
+	slots := int(exact)
+	// #nosec G404 -- peer selection does not need a secure source
+	if rand.Float64() < exact-float64(slots) {
+		slots++
+	}
+	return slots
+}
+
+// newSelectionStrategy builds the strategy described by cfg
+func newSelectionStrategy(cfg config.SelectionConfig) SelectionStrategy {
+	switch cfg.Strategy {
+	case config.SelectPriority:
+		return NewPrioritySelection()
+	case config.SelectLocality:
+		return NewLocalitySelection(cfg.LocalityLabel, cfg.RemoteFraction, nil)
+	default:
+		return NewUniformSelection()
+	}
+}
+
+// selectPeers picks up to k live members other than exclude using strategy
+func selectPeers(members Membership, strategy SelectionStrategy, k int, exclude ...types.NodeID) []types.Node {
+	local, _ := members.LocalNode()
+	return strategy.Select(local, members.RandomNodes(members.Len(), exclude...), k)
+}
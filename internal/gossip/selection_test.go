@@ -0,0 +1,176 @@
+package gossip
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+// --- Helpers --- //
+
+func labelledNodes(prefix string, n int, labels map[string]string) []types.Node {
+	nodes := make([]types.Node, n)
+	for i := range nodes {
+		nodes[i] = types.Node{
+			ID:       types.NodeID(fmt.Sprintf("%s%d", prefix, i)),
+			State:    types.StateAlive,
+			Metadata: types.NodeMetadata{Labels: labels},
+		}
+	}
+	return nodes
+}
+
+func assertDistinct(t *testing.T, nodes []types.Node) {
+	t.Helper()
+	seen := make(map[types.NodeID]bool)
+	for _, node := range nodes {
+		if seen[node.ID] {
+			t.Fatalf("%s selected twice", node.ID)
+		}
+		seen[node.ID] = true
+	}
+}
+
+func TestSelection_ReturnsUpToKDistinct(t *testing.T) {
+	local := types.Node{ID: "local", Metadata: types.NodeMetadata{Labels: map[string]string{"site": "a"}}}
+	candidates := append(labelledNodes("a", 3, map[string]string{"site": "a"}), labelledNodes("b", 3, map[string]string{"site": "b"})...)
+
+	strategies := map[string]SelectionStrategy{
+		"uniform":  NewUniformSelection(),
+		"priority": NewPrioritySelection(),
+		"locality": NewLocalitySelection("site", 0.5, nil),
+	}
+	for name, strategy := range strategies {
+		for _, k := range []int{0, 1, 4, 6, 10} {
+			got := strategy.Select(local, candidates, k)
+			if want := min(k, len(candidates)); len(got) != want {
+				t.Errorf("%s: Select(k=%d) returned %d nodes, want %d", name, k, len(got), want)
+			}
+			assertDistinct(t, got)
+		}
+	}
+}
+
+func TestPrioritySelection_FavoursHighPriority(t *testing.T) {
+	candidates := []types.Node{
+		{ID: "low", Metadata: types.NodeMetadata{Priority: 0}},
+		{ID: "high", Metadata: types.NodeMetadata{Priority: 9}},
+	}
+	strategy := NewPrioritySelection()
+
+	high := 0
+	const trials = 2000
+	for range trials {
+		if strategy.Select(types.Node{}, candidates, 1)[0].ID == "high" {
+			high++
+		}
+	}
+
+	// Expected 10/11 of the picks
+	if high < trials*8/10 {
+		t.Errorf("high priority picked %d/%d times, want about %d", high, trials, trials*10/11)
+	}
+}
+
+func TestLocalitySelection_PrefersLocalLocality(t *testing.T) {
+	local := types.Node{ID: "local", Metadata: types.NodeMetadata{Labels: map[string]string{"site": "a"}}}
+	candidates := append(labelledNodes("a", 4, map[string]string{"site": "a"}), labelledNodes("b", 4, map[string]string{"site": "b"})...)
+	strategy := NewLocalitySelection("site", 0, nil)
+
+	for range 100 {
+		for _, node := range strategy.Select(local, candidates, 3) {
+			if node.Metadata.Labels["site"] != "a" {
+				t.Fatalf("selected remote node %s with no remote share", node.ID)
+			}
+		}
+	}
+}
+
+func TestLocalitySelection_ReservesRemoteFraction(t *testing.T) {
+	local := types.Node{ID: "local", Metadata: types.NodeMetadata{Labels: map[string]string{"site": "a"}}}
+	candidates := append(labelledNodes("a", 4, map[string]string{"site": "a"}), labelledNodes("b", 4, nil)...)
+	strategy := NewLocalitySelection("site", 0.25, nil)
+
+	remote := 0
+	const trials = 2000
+	for range trials {
+		if strategy.Select(local, candidates, 1)[0].Metadata.Labels["site"] != "a" {
+			remote++
+		}
+	}
+
+	if remote < trials*15/100 || remote > trials*35/100 {
+		t.Errorf("remote picked %d/%d times, want about %d", remote, trials, trials/4)
+	}
+}
+
+func TestLocalitySelection_LocalPicksFirst(t *testing.T) {
+	local := types.Node{ID: "local", Metadata: types.NodeMetadata{Labels: map[string]string{"site": "a"}}}
+	candidates := append(labelledNodes("b", 4, map[string]string{"site": "b"}), labelledNodes("a", 4, map[string]string{"site": "a"})...)
+	strategy := NewLocalitySelection("site", 0.5, nil)
+
+	got := strategy.Select(local, candidates, len(candidates))
+	for i, node := range got {
+		if local := node.Metadata.Labels["site"] == "a"; local != (i < 4) {
+			t.Fatalf("Select = %v, want the site a nodes first", nodeIDs(got))
+		}
+	}
+}
+
+func TestLocalitySelection_FallsBackToRemote(t *testing.T) {
+	local := types.Node{ID: "local", Metadata: types.NodeMetadata{Labels: map[string]string{"site": "a"}}}
+	candidates := append(labelledNodes("a", 1, map[string]string{"site": "a"}), labelledNodes("b", 3, map[string]string{"site": "b"})...)
+	strategy := NewLocalitySelection("site", 0, nil)
+
+	got := strategy.Select(local, candidates, 3)
+	if len(got) != 3 || got[0].ID != "a0" {
+		t.Errorf("Select = %v, want the local node first and then remote ones", nodeIDs(got))
+	}
+	assertDistinct(t, got)
+}
+
+func TestLocalitySelection_WithoutLocalLabel(t *testing.T) {
+	candidates := labelledNodes("b", 3, map[string]string{"site": "b"})
+	strategy := NewLocalitySelection("site", 0, nil)
+
+	if got := strategy.Select(types.Node{ID: "local"}, candidates, 2); len(got) != 2 {
+		t.Errorf("Select returned %d nodes, want 2", len(got))
+	}
+}
+
+func TestProbeScheduler_UsesStrategyOrder(t *testing.T) {
+	membership := NewMembership()
+	for i := range 5 {
+		_ = membership.Merge(GossipEntry{
+			NodeID: types.NodeID(fmt.Sprintf("node%d", i)), State: types.StateAlive, Incarnation: 1,
+			Metadata: &types.NodeMetadata{Priority: i},
+		})
+	}
+	scheduler := NewProbeScheduler(membership, "local", byPriority{})
+
+	for round := range 2 {
+		for want := 4; want >= 0; want-- {
+			node, _ := scheduler.Next()
+			if node.Metadata.Priority != want {
+				t.Fatalf("round %d: probed priority %d, want %d", round, node.Metadata.Priority, want)
+			}
+		}
+	}
+}
+
+// byPriority orders members by descending priority, deterministically
+type byPriority struct{}
+
+func (byPriority) Select(_ types.Node, candidates []types.Node, k int) []types.Node {
+	selected := make([]types.Node, len(candidates))
+	copy(selected, candidates)
+	for i := range selected {
+		for j := i + 1; j < len(selected); j++ {
+			if selected[j].Metadata.Priority > selected[i].Metadata.Priority {
+				selected[i], selected[j] = selected[j], selected[i]
+			}
+		}
+	}
+	return selected[:min(k, len(selected))]
+}
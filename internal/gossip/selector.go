@@ -0,0 +1,244 @@
+package gossip
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var ErrInvalidSelector = errors.New("invalid label selector")
+
+// LabelSelector matches node labels against a set of requirements, using the
+// Kubernetes selector syntax: comma-separated requirements that must all
+// hold, each one of
+//
+//	key=value, key==value, key!=value
+//	key in (v1,v2), key notin (v1,v2)
+//	key, !key
+type LabelSelector interface {
+	// Matches reports whether labels satisfy every requirement
+	Matches(labels map[string]string) bool
+
+	// String returns the selector in canonical form
+	String() string
+}
+
+type selectorOp int
+
+const (
+	opEquals selectorOp = iota
+	opNotEquals
+	opIn
+	opNotIn
+	opExists
+	opNotExists
+)
+
+type requirement struct {
+	key    string
+	op     selectorOp
+	values []string // one for equality, sorted for sets, none for existence
+}
+
+type labelSelector []requirement
+
+// ParseSelector parses s into a LabelSelector. The empty selector matches
+// every node
+func ParseSelector(s string) (LabelSelector, error) {
+	parts, err := splitRequirements(s)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := make(labelSelector, 0, len(parts))
+	for _, part := range parts {
+		req, err := parseRequirement(part)
+		if err != nil {
+			return nil, err
+		}
+		selector = append(selector, req)
+	}
+	return selector, nil
+}
+
+// MustParseSelector is like ParseSelector but panics on a malformed selector
+func MustParseSelector(s string) LabelSelector {
+	selector, err := ParseSelector(s)
+	if err != nil {
+		panic(err)
+	}
+	return selector
+}
+
+func (s labelSelector) Matches(labels map[string]string) bool {
+	for _, req := range s {
+		if !req.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s labelSelector) String() string {
+	parts := make([]string, len(s))
+	for i, req := range s {
+		parts[i] = req.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func (r requirement) matches(labels map[string]string) bool {
+	value, exists := labels[r.key]
+	switch r.op {
+	case opEquals:
+		return exists && value == r.values[0]
+	case opNotEquals:
+		return !exists || value != r.values[0]
+	case opIn:
+		return exists && slices.Contains(r.values, value)
+	case opNotIn:
+		return !exists || !slices.Contains(r.values, value)
+	case opExists:
+		return exists
+	case opNotExists:
+		return !exists
+	default:
+		return false
+	}
+}
+
+func (r requirement) String() string {
+	switch r.op {
+	case opEquals:
+		return r.key + "=" + r.values[0]
+	case opNotEquals:
+		return r.key + "!=" + r.values[0]
+	case opIn:
+		return r.key + " in (" + strings.Join(r.values, ",") + ")"
+	case opNotIn:
+		return r.key + " notin (" + strings.Join(r.values, ",") + ")"
+	case opNotExists:
+		return "!" + r.key
+	default:
+		return r.key
+	}
+}
+
+// splitRequirements splits s on the commas that are not inside a value list
+func splitRequirements(s string) ([]string, error) {
+	var parts []string
+	depth, start := 0, 0
+
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+			if depth > 1 {
+				return nil, fmt.Errorf("%w: nested parentheses in %q", ErrInvalidSelector, s)
+			}
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("%w: unbalanced parentheses in %q", ErrInvalidSelector, s)
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("%w: unbalanced parentheses in %q", ErrInvalidSelector, s)
+	}
+	parts = append(parts, s[start:])
+
+	if len(parts) == 1 && strings.TrimSpace(parts[0]) == "" {
+		return nil, nil
+	}
+	return parts, nil
+}
+
+func parseRequirement(s string) (requirement, error) {
+	s = strings.TrimSpace(s)
+
+	switch {
+	case strings.Contains(s, "("):
+		return parseSetRequirement(s)
+	case strings.Contains(s, "!="):
+		return parseEqualityRequirement(s, "!=", opNotEquals)
+	case strings.Contains(s, "=="):
+		return parseEqualityRequirement(s, "==", opEquals)
+	case strings.Contains(s, "="):
+		return parseEqualityRequirement(s, "=", opEquals)
+	case strings.HasPrefix(s, "!"):
+		key := strings.TrimSpace(s[1:])
+		if !validLabel(key) || key == "" {
+			return requirement{}, fmt.Errorf("%w: bad key in %q", ErrInvalidSelector, s)
+		}
+		return requirement{key: key, op: opNotExists}, nil
+	default:
+		if !validLabel(s) || s == "" {
+			return requirement{}, fmt.Errorf("%w: bad key in %q", ErrInvalidSelector, s)
+		}
+		return requirement{key: s, op: opExists}, nil
+	}
+}
+
+func parseEqualityRequirement(s, sep string, op selectorOp) (requirement, error) {
+	key, value, _ := strings.Cut(s, sep)
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+	if key == "" || !validLabel(key) || !validLabel(value) {
+		return requirement{}, fmt.Errorf("%w: bad requirement %q", ErrInvalidSelector, s)
+	}
+	return requirement{key: key, op: op, values: []string{value}}, nil
+}
+
+func parseSetRequirement(s string) (requirement, error) {
+	open := strings.Index(s, "(")
+	if !strings.HasSuffix(s, ")") {
+		return requirement{}, fmt.Errorf("%w: trailing text after value list in %q", ErrInvalidSelector, s)
+	}
+
+	fields := strings.Fields(s[:open])
+	if len(fields) != 2 || fields[0] == "" || !validLabel(fields[0]) {
+		return requirement{}, fmt.Errorf("%w: bad requirement %q", ErrInvalidSelector, s)
+	}
+
+	req := requirement{key: fields[0]}
+	switch fields[1] {
+	case "in":
+		req.op = opIn
+	case "notin":
+		req.op = opNotIn
+	default:
+		return requirement{}, fmt.Errorf("%w: unknown operator %q in %q", ErrInvalidSelector, fields[1], s)
+	}
+
+	for _, value := range strings.Split(s[open+1:len(s)-1], ",") {
+		value = strings.TrimSpace(value)
+		if value == "" || !validLabel(value) {
+			return requirement{}, fmt.Errorf("%w: bad value list in %q", ErrInvalidSelector, s)
+		}
+		req.values = append(req.values, value)
+	}
+	slices.Sort(req.values)
+	req.values = slices.Compact(req.values)
+	return req, nil
+}
+
+// validLabel reports whether s only uses characters allowed in label keys
+// and values: alphanumerics, '-', '_', '.' and '/'
+func validLabel(s string) bool {
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == '/':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package gossip
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseSelector_Matches(t *testing.T) {
+	radio := map[string]string{"role": "radio", "team": "alpha"}
+	hq := map[string]string{"role": "server", "team": "bravo", "hq-only": "true"}
+	bare := map[string]string{}
+
+	cases := []struct {
+		selector string
+		labels   map[string]string
+		want     bool
+	}{
+		{"", bare, true},
+		{"role=radio", radio, true},
+		{"role==radio", radio, true},
+		{"role=radio", hq, false},
+		{"role!=radio", hq, true},
+		{"role!=radio", bare, true},
+		{"team in (alpha,bravo)", radio, true},
+		{"team in (alpha, bravo)", hq, true},
+		{"team in (charlie)", radio, false},
+		{"team in (alpha)", bare, false},
+		{"team notin (alpha)", radio, false},
+		{"team notin (alpha)", bare, true},
+		{"hq-only", hq, true},
+		{"hq-only", radio, false},
+		{"!hq-only", radio, true},
+		{"!hq-only", hq, false},
+		{"role=radio,team in (alpha,bravo),!hq-only", radio, true},
+		{"role=radio,team in (alpha,bravo),!hq-only", hq, false},
+		{" role = radio , !hq-only ", radio, true},
+	}
+
+	for _, tc := range cases {
+		selector, err := ParseSelector(tc.selector)
+		if err != nil {
+			t.Errorf("ParseSelector(%q) failed: %v", tc.selector, err)
+			continue
+		}
+		if got := selector.Matches(tc.labels); got != tc.want {
+			t.Errorf("%q.Matches(%v) = %v, want %v", tc.selector, tc.labels, got, tc.want)
+		}
+	}
+}
+
+func TestParseSelector_Invalid(t *testing.T) {
+	invalid := []string{
+		"=radio",
+		"role=ra dio",
+		"role=radio,",
+		",role=radio",
+		"!",
+		"team in (alpha",
+		"team in alpha)",
+		"team in ()",
+		"team in (alpha,)",
+		"team within (alpha)",
+		"team in ((alpha))",
+		"team in (alpha) extra",
+		"role=radio=x",
+	}
+
+	for _, s := range invalid {
+		if _, err := ParseSelector(s); !errors.Is(err, ErrInvalidSelector) {
+			t.Errorf("ParseSelector(%q): err = %v, want ErrInvalidSelector", s, err)
+		}
+	}
+}
+
+func TestParseSelector_String(t *testing.T) {
+	selector := MustParseSelector("role==radio, team in (bravo,alpha,bravo),!hq-only,zone, tier notin (edge)")
+
+	want := "role=radio,team in (alpha,bravo),!hq-only,zone,tier notin (edge)"
+	if got := selector.String(); got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+
+	// The canonical form parses back to the same selector
+	if again := MustParseSelector(want).String(); again != want {
+		t.Errorf("reparsed String = %q, want %q", again, want)
+	}
+}
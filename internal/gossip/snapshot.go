@@ -0,0 +1,101 @@
+package gossip
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+// snapshotFile is the name of the membership snapshot inside the data dir
+const snapshotFile = "membership.json"
+
+// membershipSnapshot is the on-disk form of a membership
+type membershipSnapshot struct {
+	SavedAt time.Time
+	Nodes   []types.Node
+}
+
+// saveSnapshot writes nodes to dir. The file is written under a temporary
+// name and renamed into place, so a crash never leaves a torn snapshot
+func saveSnapshot(dir string, nodes []types.Node) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(membershipSnapshot{SavedAt: time.Now(), Nodes: nodes}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, snapshotFile+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, snapshotFile))
+}
+
+// loadSnapshot reads the snapshot saved in dir. Returns an error wrapping
+// os.ErrNotExist if there is none
+func loadSnapshot(dir string) (membershipSnapshot, error) {
+	var snap membershipSnapshot
+
+	data, err := os.ReadFile(filepath.Join(dir, snapshotFile))
+	if err != nil {
+		return snap, err
+	}
+	err = json.Unmarshal(data, &snap)
+	return snap, err
+}
+
+// restoreSnapshot loads the snapshot from the data dir into the membership
+// and remembers the live peers in it as rejoin candidates
+func (g *gossiper) restoreSnapshot() error {
+	snap, err := loadSnapshot(g.cfg.Node.DataDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // first boot
+		}
+		return err
+	}
+
+	g.members.Restore(snap.Nodes)
+
+	for _, node := range snap.Nodes {
+		if node.ID != types.NodeID(g.cfg.Node.ID) && isLiveState(node.State) {
+			g.rejoin = append(g.rejoin, node.Address)
+		}
+	}
+	return nil
+}
+
+// snapshotLoop saves the membership every SnapshotInterval
+func (g *gossiper) snapshotLoop(ctx context.Context) {
+	ticker := time.NewTicker(g.cfg.Membership.SnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = saveSnapshot(g.cfg.Node.DataDir, g.members.AllNodes()) // retried next tick
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package gossip
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+func TestSnapshot_SaveAndLoad(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	nodes := []types.Node{
+		{
+			ID:          "node1",
+			Address:     "10.0.0.1:7946",
+			State:       types.StateAlive,
+			Incarnation: 3,
+			Metadata:    types.NodeMetadata{Labels: map[string]string{"zone": "a"}, Priority: 2},
+		},
+		{ID: "node2", Address: "10.0.0.2:7946", State: types.StateDead, Incarnation: 7},
+	}
+
+	if err := saveSnapshot(dir, nodes); err != nil {
+		t.Fatalf("saveSnapshot failed: %v", err)
+	}
+
+	snap, err := loadSnapshot(dir)
+	if err != nil {
+		t.Fatalf("loadSnapshot failed: %v", err)
+	}
+	if len(snap.Nodes) != 2 {
+		t.Fatalf("loaded %d nodes, want 2", len(snap.Nodes))
+	}
+	got := snap.Nodes[0]
+	if got.ID != "node1" || got.Address != "10.0.0.1:7946" || got.Incarnation != 3 || got.Metadata.Labels["zone"] != "a" {
+		t.Errorf("node = %+v, want node1 as saved", got)
+	}
+	if snap.Nodes[1].State != types.StateDead {
+		t.Errorf("state = %s, want dead", snap.Nodes[1].State)
+	}
+
+	// Only the snapshot itself is left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != snapshotFile {
+		t.Errorf("data dir holds %d entries, want only %s", len(entries), snapshotFile)
+	}
+}
+
+func TestSnapshot_LoadMissing(t *testing.T) {
+	if _, err := loadSnapshot(t.TempDir()); !os.IsNotExist(err) {
+		t.Errorf("err = %v, want not exist", err)
+	}
+}
+
+func TestSnapshot_SaveReplacesPrevious(t *testing.T) {
+	dir := t.TempDir()
+
+	_ = saveSnapshot(dir, []types.Node{{ID: "old"}})
+	if err := saveSnapshot(dir, []types.Node{{ID: "new"}}); err != nil {
+		t.Fatalf("saveSnapshot failed: %v", err)
+	}
+
+	snap, _ := loadSnapshot(dir)
+	if len(snap.Nodes) != 1 || snap.Nodes[0].ID != "new" {
+		t.Errorf("nodes = %v, want only the newest snapshot", snap.Nodes)
+	}
+}
+
+func TestGossiper_RejoinsFromSnapshot(t *testing.T) {
+	network := NewTestNetwork()
+	g1 := newTestGossiper(t, network, "node1")
+
+	cfg := testGossiperConfig("node2")
+	cfg.Node.DataDir = t.TempDir()
+
+	first, err := NewGossiper(cfg, network.NewTransport("node2"), NewCodec())
+	if err != nil {
+		t.Fatalf("failed to create gossiper: %v", err)
+	}
+	if err := first.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start gossiper: %v", err)
+	}
+	if err := first.Join("node1"); err != nil {
+		t.Fatalf("node2 failed to join: %v", err)
+	}
+	waitFor(t, 3*time.Second, func() bool { return knowsAlive(first, "node1") }, "node2 to learn node1")
+
+	saved, _ := first.Members().LocalNode()
+	if err := first.Shutdown(); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	// A reboot: same data dir, no seeds
+	second, err := NewGossiper(cfg, network.NewTransport("node2"), NewCodec())
+	if err != nil {
+		t.Fatalf("failed to restore gossiper: %v", err)
+	}
+	if local, _ := second.Members().LocalNode(); local.Incarnation <= saved.Incarnation {
+		t.Errorf("incarnation = %d after restore, want > %d", local.Incarnation, saved.Incarnation)
+	}
+	if _, exists := second.Members().GetNode("node1"); !exists {
+		t.Error("node1 was not restored")
+	}
+
+	if err := second.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start gossiper: %v", err)
+	}
+	t.Cleanup(func() { _ = second.Shutdown() })
+	if err := second.Join(); err != nil {
+		t.Fatalf("rejoin from snapshot failed: %v", err)
+	}
+
+	waitFor(t, 3*time.Second, func() bool {
+		node, exists := g1.Members().GetNode("node2")
+		return exists && node.State == types.StateAlive && node.Incarnation > saved.Incarnation
+	}, "node1 to see node2's new incarnation")
+}
+
+func TestGossiper_CorruptSnapshotFails(t *testing.T) {
+	cfg := testGossiperConfig("node1")
+	cfg.Node.DataDir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(cfg.Node.DataDir, snapshotFile), []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewGossiper(cfg, NewTestNetwork().NewTransport("node1"), NewCodec()); err == nil {
+		t.Error("NewGossiper accepted a corrupt snapshot")
+	}
+}
@@ -0,0 +1,166 @@
+package gossip
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/michael-martinez-dev/adaptive-hive/internal/config"
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+// SuspicionManager declares suspect members dead once their suspicion
+// timeout expires. Following Lifeguard, the timeout starts long and shrinks
+// towards a floor as other nodes independently confirm the suspicion
+type SuspicionManager interface {
+	// Suspect starts the suspicion timer for id at incarnation, or counts
+	// from as a confirmation if one is already running. from is the node
+	// that raised the suspicion, not whoever passed it along; if it is
+	// unknown the suspicion is not counted as a confirmation. Returns true
+	// if a timer was started or shortened
+	Suspect(id types.NodeID, incarnation uint64, from types.NodeID) bool
+
+	// Refute cancels the suspicion timer for id
+	Refute(id types.NodeID)
+
+	// Stop cancels every running suspicion timer
+	Stop()
+}
+
+type suspicionManager struct {
+	cfg     config.FailureDetectorConfig
+	members Membership
+
+	mu         sync.Mutex
+	suspicions map[types.NodeID]*suspicion
+}
+
+// suspicion tracks the timer and confirmations for one suspect member
+type suspicion struct {
+	incarnation uint64
+	start       time.Time
+	min         time.Duration
+	max         time.Duration
+	expected    int // confirmations needed to reach min
+	confirmers  map[types.NodeID]bool
+	timer       *time.Timer
+}
+
+func NewSuspicionManager(cfg config.FailureDetectorConfig, members Membership) SuspicionManager {
+	return &suspicionManager{
+		cfg:        cfg,
+		members:    members,
+		suspicions: make(map[types.NodeID]*suspicion),
+	}
+}
+
+func (m *suspicionManager) Suspect(id types.NodeID, incarnation uint64, from types.NodeID) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, exists := m.suspicions[id]
+	if exists && incarnation < s.incarnation {
+		return false // stale rumor about an older incarnation
+	}
+
+	if !exists || incarnation > s.incarnation {
+		if exists {
+			s.timer.Stop()
+		}
+		m.start(id, incarnation, from)
+		return true
+	}
+
+	if from == "" || s.confirmers[from] {
+		return false
+	}
+	s.confirmers[from] = true
+
+	remaining := s.timeout() - time.Since(s.start)
+	s.timer.Reset(max(remaining, 0))
+	return true
+}
+
+func (m *suspicionManager) Refute(id types.NodeID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if s, exists := m.suspicions[id]; exists {
+		s.timer.Stop()
+		delete(m.suspicions, id)
+	}
+}
+
+func (m *suspicionManager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, s := range m.suspicions {
+		s.timer.Stop()
+		delete(m.suspicions, id)
+	}
+}
+
+// start must be called with the lock held
+func (m *suspicionManager) start(id types.NodeID, incarnation uint64, from types.NodeID) {
+	n := m.members.Len()
+	minTimeout := suspicionTimeout(m.cfg.SuspicionMult, n, m.cfg.ProbeInterval)
+
+	// The suspicion we raised ourselves is not an independent confirmation
+	expected := max(m.cfg.SuspicionMult-2, 0)
+	if n-2 < expected {
+		expected = 0
+	}
+
+	s := &suspicion{
+		incarnation: incarnation,
+		start:       time.Now(),
+		min:         minTimeout,
+		max:         time.Duration(m.cfg.SuspicionMaxTimeoutMult) * minTimeout,
+		expected:    expected,
+		confirmers:  make(map[types.NodeID]bool),
+	}
+	if from != "" {
+		s.confirmers[from] = true
+	}
+	s.timer = time.AfterFunc(s.timeout(), func() { m.expire(id, s) })
+	m.suspicions[id] = s
+}
+
+// expire declares id dead if it is still suspect at the incarnation the
+// timer was started for
+func (m *suspicionManager) expire(id types.NodeID, s *suspicion) {
+	m.mu.Lock()
+	if m.suspicions[id] != s {
+		m.mu.Unlock()
+		return // refuted or restarted
+	}
+	delete(m.suspicions, id)
+	m.mu.Unlock()
+
+	node, exists := m.members.GetNode(id)
+	if !exists || node.State != types.StateSuspect || node.Incarnation != s.incarnation {
+		return
+	}
+	m.members.Dead(id)
+}
+
+// timeout interpolates between max and min on a log scale of the number
+// of independent confirmations received so far
+func (s *suspicion) timeout() time.Duration {
+	if s.expected < 1 {
+		return s.min
+	}
+
+	confirmations := max(len(s.confirmers)-1, 0)
+	frac := math.Log(float64(confirmations)+1) / math.Log(float64(s.expected)+1)
+	timeout := s.max - time.Duration(frac*float64(s.max-s.min))
+	return max(timeout, s.min)
+}
+
+// suspicionTimeout is SuspicionMult * log(N) * ProbeInterval, where log(N)
+// never drops below one so small clusters still get a full interval
+func suspicionTimeout(mult, n int, interval time.Duration) time.Duration {
+	nodeScale := math.Max(1, math.Log10(math.Max(1, float64(n))))
+	return time.Duration(float64(mult) * nodeScale * float64(interval))
+}
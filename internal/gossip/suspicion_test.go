@@ -0,0 +1,181 @@
+package gossip
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/michael-martinez-dev/adaptive-hive/internal/config"
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+// --- Helpers --- //
+
+func newSuspectMembership(t *testing.T, size int) *recordingMembership {
+	t.Helper()
+	members := &recordingMembership{Membership: NewMembership()}
+	for i := range size {
+		_ = members.Merge(GossipEntry{NodeID: types.NodeID(fmt.Sprintf("node%d", i)), State: types.StateAlive, Incarnation: 1})
+	}
+	_ = members.Merge(GossipEntry{NodeID: "node0", State: types.StateSuspect, Incarnation: 1})
+	return members
+}
+
+func waitForDead(t *testing.T, members *recordingMembership, id types.NodeID, timeout time.Duration) time.Duration {
+	t.Helper()
+	start := time.Now()
+	deadline := time.After(timeout)
+	for !members.wasDeclaredDead(id) {
+		select {
+		case <-deadline:
+			t.Fatalf("%s was not declared dead within %s", id, timeout)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	return time.Since(start)
+}
+
+func testSuspicionConfig() config.FailureDetectorConfig {
+	return config.FailureDetectorConfig{
+		ProbeInterval:           20 * time.Millisecond,
+		ProbeTimeout:            10 * time.Millisecond,
+		IndirectNodes:           3,
+		SuspicionMult:           5,
+		SuspicionMaxTimeoutMult: 10,
+	}
+}
+
+func TestSuspicionTimeout_ScalesWithClusterSize(t *testing.T) {
+	interval := time.Second
+
+	if got := suspicionTimeout(4, 1, interval); got != 4*time.Second {
+		t.Errorf("timeout(1 node) = %s, want 4s", got)
+	}
+
+	if got := suspicionTimeout(4, 10, interval); got != 4*time.Second {
+		t.Errorf("timeout(10 nodes) = %s, want 4s", got)
+	}
+
+	if got := suspicionTimeout(4, 100, interval); got != 8*time.Second {
+		t.Errorf("timeout(100 nodes) = %s, want 8s", got)
+	}
+}
+
+func TestSuspicionManager_UnconfirmedSuspicionUsesMaxTimeout(t *testing.T) {
+	members := newSuspectMembership(t, 5)
+	manager := NewSuspicionManager(testSuspicionConfig(), members)
+	defer manager.Stop()
+
+	if !manager.Suspect("node0", 1, "node1") {
+		t.Fatal("Suspect did not start a timer")
+	}
+
+	// min = 5 * 20ms = 100ms, max = 10 * min = 1s
+	time.Sleep(200 * time.Millisecond)
+	if members.wasDeclaredDead("node0") {
+		t.Fatal("unconfirmed suspicion expired at the min timeout")
+	}
+
+	waitForDead(t, members, "node0", 2*time.Second)
+}
+
+func TestSuspicionManager_ConfirmationsShortenTimeout(t *testing.T) {
+	members := newSuspectMembership(t, 5)
+	manager := NewSuspicionManager(testSuspicionConfig(), members)
+	defer manager.Stop()
+
+	manager.Suspect("node0", 1, "node1")
+
+	// SuspicionMult-2 = 3 independent confirmations reach the floor
+	for _, from := range []types.NodeID{"node2", "node3", "node4"} {
+		if !manager.Suspect("node0", 1, from) {
+			t.Errorf("confirmation from %s was not counted", from)
+		}
+	}
+
+	elapsed := waitForDead(t, members, "node0", time.Second)
+	if elapsed > 500*time.Millisecond {
+		t.Errorf("confirmed suspicion took %s, want close to the 100ms floor", elapsed)
+	}
+}
+
+func TestSuspicionManager_DuplicateConfirmationIgnored(t *testing.T) {
+	members := newSuspectMembership(t, 5)
+	manager := NewSuspicionManager(testSuspicionConfig(), members)
+	defer manager.Stop()
+
+	manager.Suspect("node0", 1, "node1")
+
+	if manager.Suspect("node0", 1, "node1") {
+		t.Error("repeated suspicion from the same node was counted")
+	}
+
+	if !manager.Suspect("node0", 1, "node2") {
+		t.Error("first confirmation from node2 was not counted")
+	}
+
+	if manager.Suspect("node0", 1, "node2") {
+		t.Error("repeated confirmation from node2 was counted")
+	}
+}
+
+func TestSuspicionManager_UnknownOriginatorNeverConfirms(t *testing.T) {
+	members := newSuspectMembership(t, 5)
+	manager := NewSuspicionManager(testSuspicionConfig(), members)
+	defer manager.Stop()
+
+	if !manager.Suspect("node0", 1, "") {
+		t.Fatal("suspicion from an unknown originator did not start a timer")
+	}
+	if manager.Suspect("node0", 1, "") {
+		t.Error("suspicion from an unknown originator was counted as a confirmation")
+	}
+}
+
+func TestSuspicionManager_RefuteCancelsTimer(t *testing.T) {
+	cfg := testSuspicionConfig()
+	cfg.SuspicionMaxTimeoutMult = 1
+
+	members := newSuspectMembership(t, 2)
+	manager := NewSuspicionManager(cfg, members)
+	defer manager.Stop()
+
+	manager.Suspect("node0", 1, "node1")
+	manager.Refute("node0")
+
+	time.Sleep(250 * time.Millisecond)
+	if members.wasDeclaredDead("node0") {
+		t.Error("refuted suspicion still declared the node dead")
+	}
+}
+
+func TestSuspicionManager_IgnoresNodeThatRefutedViaIncarnation(t *testing.T) {
+	cfg := testSuspicionConfig()
+	cfg.SuspicionMaxTimeoutMult = 1
+
+	members := newSuspectMembership(t, 2)
+	manager := NewSuspicionManager(cfg, members)
+	defer manager.Stop()
+
+	manager.Suspect("node0", 1, "node1")
+
+	// node0 refutes with a higher incarnation before the timer fires
+	_ = members.Merge(GossipEntry{NodeID: "node0", State: types.StateAlive, Incarnation: 2})
+
+	time.Sleep(250 * time.Millisecond)
+	if members.wasDeclaredDead("node0") {
+		t.Error("node alive at a newer incarnation was declared dead")
+	}
+}
+
+func TestSuspicionManager_StaleIncarnationIgnored(t *testing.T) {
+	members := newSuspectMembership(t, 5)
+	manager := NewSuspicionManager(testSuspicionConfig(), members)
+	defer manager.Stop()
+
+	manager.Suspect("node0", 2, "node1")
+
+	if manager.Suspect("node0", 1, "node2") {
+		t.Error("suspicion of an older incarnation was counted")
+	}
+}
@@ -0,0 +1,385 @@
+package gossip
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/michael-martinez-dev/adaptive-hive/internal/config"
+)
+
+// tcpRequestBuffer is how many received requests are held before connections wait
+const tcpRequestBuffer = 16
+
+// frameHeaderSize is the length prefix written before every frame
+const frameHeaderSize = 4
+
+type tcpTransport struct {
+	bindAddr     string
+	timeout      time.Duration
+	maxFrameSize int
+
+	// slots bounds the number of outbound requests in flight
+	slots chan struct{}
+	pool  *connPool
+
+	mu       sync.RWMutex
+	listener net.Listener
+	conns    map[net.Conn]struct{} // inbound connections being served
+	reqCh    chan *StreamRequest
+	running  bool
+	stopped  bool
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewTCPTransport creates a StreamTransport that exchanges length-prefixed
+// frames over TCP on BindAddr:BindPort. Outbound connections are pooled and
+// bounded by MaxStreamConns, and every exchange is bounded by StreamTimeout
+func NewTCPTransport(node config.NodeConfig, gossip config.GossipConfig) StreamTransport {
+	return &tcpTransport{
+		bindAddr:     net.JoinHostPort(node.BindAddr, node.BindPort),
+		timeout:      gossip.StreamTimeout,
+		maxFrameSize: gossip.MaxStreamFrameSize,
+		slots:        make(chan struct{}, gossip.MaxStreamConns),
+		pool:         newConnPool(gossip.MaxStreamConns, gossip.StreamTimeout/2),
+		conns:        make(map[net.Conn]struct{}),
+		reqCh:        make(chan *StreamRequest, tcpRequestBuffer),
+		done:         make(chan struct{}),
+	}
+}
+
+// Start begins accepting connections. The transport stops itself when ctx
+// is cancelled. A stopped transport cannot be restarted
+func (t *tcpTransport) Start(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped {
+		return ErrTransportStopped
+	}
+	if t.running {
+		return ErrTransportStarted
+	}
+
+	listener, err := net.Listen("tcp", t.bindAddr)
+	if err != nil {
+		return err
+	}
+
+	t.listener = listener
+	t.running = true
+
+	t.wg.Add(1)
+	go t.acceptLoop()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = t.Stop()
+		case <-t.done:
+		}
+	}()
+
+	return nil
+}
+
+// Stop closes the listener, every inbound and pooled connection, and then
+// the Requests channel
+func (t *tcpTransport) Stop() error {
+	t.mu.Lock()
+	if !t.running {
+		t.mu.Unlock()
+		return nil
+	}
+	t.running = false
+	t.stopped = true
+	close(t.done)
+	err := t.listener.Close()
+	for conn := range t.conns {
+		_ = conn.Close()
+	}
+	t.mu.Unlock()
+
+	t.wg.Wait()
+	t.pool.close()
+	close(t.reqCh)
+	return err
+}
+
+func (t *tcpTransport) Request(addr string, msg []byte) ([]byte, error) {
+	if len(msg) > t.maxFrameSize {
+		return nil, ErrFrameTooLarge
+	}
+	if !t.isRunning() {
+		return nil, ErrTransportStopped
+	}
+
+	select {
+	case t.slots <- struct{}{}:
+		defer func() { <-t.slots }()
+	case <-time.After(t.timeout):
+		return nil, errors.New("timed out waiting for a stream connection")
+	case <-t.done:
+		return nil, ErrTransportStopped
+	}
+
+	// A pooled connection may have been closed by the peer while idle,
+	// so a failure on one is retried once on a fresh connection
+	if conn, ok := t.pool.get(addr); ok {
+		resp, err := t.exchange(conn, msg)
+		if err == nil {
+			t.pool.put(addr, conn)
+			return resp, nil
+		}
+		_ = conn.Close()
+
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, err // the peer is slow, not gone
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, t.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.exchange(conn, msg)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	t.pool.put(addr, conn)
+	return resp, nil
+}
+
+func (t *tcpTransport) Requests() <-chan *StreamRequest {
+	return t.reqCh
+}
+
+func (t *tcpTransport) LocalAddr() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.listener == nil {
+		return t.bindAddr
+	}
+	return t.listener.Addr().String()
+}
+
+func (t *tcpTransport) isRunning() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.running
+}
+
+// exchange writes one request frame and reads one response frame
+func (t *tcpTransport) exchange(conn net.Conn, msg []byte) ([]byte, error) {
+	if err := conn.SetDeadline(time.Now().Add(t.timeout)); err != nil {
+		return nil, err
+	}
+	if err := writeFrame(conn, msg, t.maxFrameSize); err != nil {
+		return nil, err
+	}
+	return readFrame(conn, t.maxFrameSize)
+}
+
+func (t *tcpTransport) acceptLoop() {
+	defer t.wg.Done()
+
+	for {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		t.mu.Lock()
+		if !t.running {
+			t.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		t.conns[conn] = struct{}{}
+		t.wg.Add(1)
+		t.mu.Unlock()
+
+		go t.serveConn(conn)
+	}
+}
+
+// serveConn answers requests on an inbound connection until the peer
+// closes it, it sits idle past the timeout, or the transport stops
+func (t *tcpTransport) serveConn(conn net.Conn) {
+	defer t.wg.Done()
+	defer func() {
+		t.mu.Lock()
+		delete(t.conns, conn)
+		t.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(t.timeout)); err != nil {
+			return
+		}
+		payload, err := readFrame(conn, t.maxFrameSize)
+		if err != nil {
+			return
+		}
+
+		req := newStreamRequest(payload, conn.RemoteAddr().String())
+		deadline := time.NewTimer(t.timeout)
+
+		select {
+		case t.reqCh <- req:
+		case <-deadline.C:
+			return
+		case <-t.done:
+			deadline.Stop()
+			return
+		}
+
+		var resp []byte
+		select {
+		case resp = <-req.respCh:
+		case <-deadline.C:
+			return
+		case <-t.done:
+			deadline.Stop()
+			return
+		}
+		deadline.Stop()
+
+		if resp == nil {
+			return
+		}
+
+		if err := conn.SetWriteDeadline(time.Now().Add(t.timeout)); err != nil {
+			return
+		}
+		if err := writeFrame(conn, resp, t.maxFrameSize); err != nil {
+			return
+		}
+	}
+}
+
+// writeFrame writes msg prefixed with its big-endian uint32 length
+func writeFrame(w io.Writer, msg []byte, maxFrameSize int) error {
+	if len(msg) > maxFrameSize {
+		return ErrFrameTooLarge
+	}
+
+	frame := make([]byte, frameHeaderSize+len(msg))
+	binary.BigEndian.PutUint32(frame, uint32(len(msg))) // #nosec G115 -- bounded by maxFrameSize
+	copy(frame[frameHeaderSize:], msg)
+
+	_, err := w.Write(frame)
+	return err
+}
+
+// readFrame reads a single length-prefixed frame
+func readFrame(r io.Reader, maxFrameSize int) ([]byte, error) {
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if uint64(size) > uint64(maxFrameSize) {
+		return nil, ErrFrameTooLarge
+	}
+
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// connPool keeps idle outbound connections for reuse, bounded in total
+type connPool struct {
+	mu      sync.Mutex
+	maxIdle int
+	maxAge  time.Duration
+	idle    []pooledConn
+	closed  bool
+}
+
+type pooledConn struct {
+	addr     string
+	conn     net.Conn
+	lastUsed time.Time
+}
+
+func newConnPool(maxIdle int, maxAge time.Duration) *connPool {
+	return &connPool{
+		maxIdle: maxIdle,
+		maxAge:  maxAge,
+	}
+}
+
+// get returns the most recently used idle connection to addr, discarding
+// any that have been idle for too long
+func (p *connPool) get(addr string) (net.Conn, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := len(p.idle) - 1; i >= 0; i-- {
+		pc := p.idle[i]
+		if pc.addr != addr {
+			continue
+		}
+		p.idle = append(p.idle[:i], p.idle[i+1:]...)
+		if time.Since(pc.lastUsed) > p.maxAge {
+			_ = pc.conn.Close()
+			continue
+		}
+		return pc.conn, true
+	}
+	return nil, false
+}
+
+// put returns conn to the pool, evicting the oldest idle connection when full
+func (p *connPool) put(addr string, conn net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed || p.maxIdle <= 0 {
+		_ = conn.Close()
+		return
+	}
+
+	if len(p.idle) >= p.maxIdle {
+		_ = p.idle[0].conn.Close()
+		p.idle = p.idle[1:]
+	}
+	p.idle = append(p.idle, pooledConn{addr: addr, conn: conn, lastUsed: time.Now()})
+}
+
+// close closes every idle connection; later puts close their conn immediately
+func (p *connPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, pc := range p.idle {
+		_ = pc.conn.Close()
+	}
+	p.idle = nil
+	p.closed = true
+}
+
+func (p *connPool) len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.idle)
+}
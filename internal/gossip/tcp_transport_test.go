@@ -0,0 +1,164 @@
+package gossip
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/michael-martinez-dev/adaptive-hive/internal/config"
+)
+
+// --- Helpers --- //
+
+func newLoopbackTCPTransport(t *testing.T, maxConns int) StreamTransport {
+	t.Helper()
+	node := config.NodeConfig{BindAddr: "127.0.0.1", BindPort: "0"}
+	gossip := config.GossipConfig{
+		StreamTimeout:      time.Second,
+		MaxStreamConns:     maxConns,
+		MaxStreamFrameSize: 1024,
+	}
+
+	transport := NewTCPTransport(node, gossip)
+	if err := transport.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start tcp transport: %v", err)
+	}
+	t.Cleanup(func() { _ = transport.Stop() })
+	return transport
+}
+
+// serveEcho answers every request with the payload prefixed by "echo:"
+func serveEcho(transport StreamTransport) {
+	go func() {
+		for req := range transport.Requests() {
+			req.Respond(append([]byte("echo:"), req.Payload...))
+		}
+	}()
+}
+
+func TestTCPTransport_RequestResponse(t *testing.T) {
+	client := newLoopbackTCPTransport(t, 4)
+	server := newLoopbackTCPTransport(t, 4)
+	serveEcho(server)
+
+	resp, err := client.Request(server.LocalAddr(), []byte("hello"))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	if string(resp) != "echo:hello" {
+		t.Errorf("response = %q, want %q", resp, "echo:hello")
+	}
+}
+
+func TestTCPTransport_ReusesPooledConnection(t *testing.T) {
+	client := newLoopbackTCPTransport(t, 4)
+	server := newLoopbackTCPTransport(t, 4)
+	serveEcho(server)
+
+	for i := range 5 {
+		if _, err := client.Request(server.LocalAddr(), []byte("hello")); err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+	}
+
+	pool := client.(*tcpTransport).pool
+	if pool.len() != 1 {
+		t.Errorf("pooled connections = %d, want 1", pool.len())
+	}
+}
+
+func TestTCPTransport_PoolIsBounded(t *testing.T) {
+	client := newLoopbackTCPTransport(t, 1)
+	s1 := newLoopbackTCPTransport(t, 1)
+	s2 := newLoopbackTCPTransport(t, 1)
+	serveEcho(s1)
+	serveEcho(s2)
+
+	for _, server := range []StreamTransport{s1, s2, s1} {
+		if _, err := client.Request(server.LocalAddr(), []byte("hello")); err != nil {
+			t.Fatalf("request to %s failed: %v", server.LocalAddr(), err)
+		}
+	}
+
+	pool := client.(*tcpTransport).pool
+	if pool.len() != 1 {
+		t.Errorf("pooled connections = %d, want 1", pool.len())
+	}
+}
+
+func TestTCPTransport_RequestTooLargeFails(t *testing.T) {
+	client := newLoopbackTCPTransport(t, 4)
+
+	_, err := client.Request("127.0.0.1:1", []byte(strings.Repeat("x", 1025)))
+	if err != ErrFrameTooLarge {
+		t.Errorf("err = %v, want ErrFrameTooLarge", err)
+	}
+}
+
+func TestTCPTransport_NilResponseFailsRequest(t *testing.T) {
+	client := newLoopbackTCPTransport(t, 4)
+	server := newLoopbackTCPTransport(t, 4)
+
+	go func() {
+		for req := range server.Requests() {
+			req.Respond(nil)
+		}
+	}()
+
+	if _, err := client.Request(server.LocalAddr(), []byte("hello")); err == nil {
+		t.Error("request should have failed without a response")
+	}
+}
+
+func TestTCPTransport_StopClosesRequests(t *testing.T) {
+	server := newLoopbackTCPTransport(t, 4)
+
+	if err := server.Stop(); err != nil {
+		t.Fatalf("failed to stop: %v", err)
+	}
+
+	select {
+	case _, ok := <-server.Requests():
+		if ok {
+			t.Error("unexpected request after stop")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for requests channel to close")
+	}
+
+	if _, err := server.Request("127.0.0.1:1", []byte("hello")); err != ErrTransportStopped {
+		t.Errorf("err = %v, want ErrTransportStopped", err)
+	}
+}
+
+func TestTCPTransport_FrameRoundtrip(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := writeFrame(&buf, []byte("hello"), 16); err != nil {
+		t.Fatalf("writeFrame failed: %v", err)
+	}
+
+	msg, err := readFrame(&buf, 16)
+	if err != nil {
+		t.Fatalf("readFrame failed: %v", err)
+	}
+
+	if string(msg) != "hello" {
+		t.Errorf("frame = %q, want %q", msg, "hello")
+	}
+}
+
+func TestTCPTransport_ReadFrameRejectsOversizedLength(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := writeFrame(&buf, []byte(strings.Repeat("x", 32)), 64); err != nil {
+		t.Fatalf("writeFrame failed: %v", err)
+	}
+
+	if _, err := readFrame(&buf, 16); err != ErrFrameTooLarge {
+		t.Errorf("err = %v, want ErrFrameTooLarge", err)
+	}
+}
@@ -10,16 +10,13 @@ import (
 	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
 )
 
-var (
-	ErrTransportStopped = errors.New("transport is stopped")
-)
-
 // TestNetwork simulates a network for testing gossip protocols
 // It allows multiple TestTransports to communicate and supports
 // fault injection like partitions, latency, and packet loss
 type TestNetwork struct {
 	mu         sync.RWMutex
 	transports map[string]*TestTransport
+	streams    map[string]*TestStreamTransport
 	partitions map[string]map[string]bool // addr -> set of unreachable addrs
 }
 
@@ -27,6 +24,7 @@ type TestNetwork struct {
 func NewTestNetwork() *TestNetwork {
 	return &TestNetwork{
 		transports: make(map[string]*TestTransport),
+		streams:    make(map[string]*TestStreamTransport),
 		partitions: make(map[string]map[string]bool),
 	}
 }
@@ -46,6 +44,23 @@ func (n *TestNetwork) NewTransport(addr string) *TestTransport {
 	return t
 }
 
+// NewStreamTransport creates a new TestStreamTransport attached to this network
+// Stream and datagram transports on the same address share partitions
+func (n *TestNetwork) NewStreamTransport(addr string) *TestStreamTransport {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	t := &TestStreamTransport{
+		addr:    addr,
+		reqCh:   make(chan *StreamRequest, 100),
+		network: n,
+		timeout: time.Second,
+		done:    make(chan struct{}),
+	}
+	n.streams[addr] = t
+	return t
+}
+
 // Partition makes 'from' unable to reach any of the 'unreachable' nodes
 // Partitions are bidirectional by default
 func (n *TestNetwork) Partition(from string, unreachable ...string) {
@@ -120,6 +135,39 @@ func (n *TestNetwork) send(from, to string, msg []byte) error {
 	return nil
 }
 
+// request delivers a stream request from one transport to another and
+// waits for the response. Unlike send, failures are reported to the caller
+func (n *TestNetwork) request(from, to string, msg []byte, timeout time.Duration) ([]byte, error) {
+	n.mu.RLock()
+	parted := n.partitions[from][to]
+	target, ok := n.streams[to]
+	n.mu.RUnlock()
+
+	if parted || !ok || !target.isRunning() {
+		return nil, ErrUnreachable
+	}
+
+	req := newStreamRequest(msg, from)
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	if err := target.deliver(req, deadline.C); err != nil {
+		return nil, err
+	}
+
+	select {
+	case resp := <-req.respCh:
+		if resp == nil {
+			return nil, ErrNoResponse
+		}
+		return resp, nil
+	case <-target.done:
+		return nil, ErrUnreachable
+	case <-deadline.C:
+		return nil, ErrRequestTimeout
+	}
+}
+
 // TestTransport implements Transport for testing
 type TestTransport struct {
 	addr     string
@@ -178,3 +226,103 @@ func (t *TestTransport) isRunning() bool {
 
 	return t.running
 }
+
+var (
+	ErrUnreachable    = errors.New("address unreachable")
+	ErrRequestTimeout = errors.New("request timed out")
+)
+
+// TestStreamTransport implements StreamTransport for testing
+type TestStreamTransport struct {
+	addr    string
+	reqCh   chan *StreamRequest
+	network *TestNetwork
+	timeout time.Duration
+
+	mu       sync.RWMutex
+	running  bool
+	stopped  bool
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func (t *TestStreamTransport) SetTimeout(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.timeout = d
+}
+
+func (t *TestStreamTransport) Start(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped {
+		return ErrTransportStopped
+	}
+	t.running = true
+	return nil
+}
+
+func (t *TestStreamTransport) Stop() error {
+	// Closing done first releases any deliver blocked on a full channel
+	t.stopOnce.Do(func() { close(t.done) })
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped {
+		return nil
+	}
+	t.running = false
+	t.stopped = true
+	close(t.reqCh)
+	return nil
+}
+
+func (t *TestStreamTransport) Request(addr string, msg []byte) ([]byte, error) {
+	if !t.isRunning() {
+		return nil, ErrTransportStopped
+	}
+
+	t.mu.RLock()
+	timeout := t.timeout
+	t.mu.RUnlock()
+
+	return t.network.request(t.addr, addr, msg, timeout)
+}
+
+func (t *TestStreamTransport) Requests() <-chan *StreamRequest {
+	return t.reqCh
+}
+
+func (t *TestStreamTransport) LocalAddr() string {
+	return t.addr
+}
+
+func (t *TestStreamTransport) isRunning() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.running
+}
+
+// deliver queues req for this transport, holding the read lock so the
+// channel cannot be closed underneath the send
+func (t *TestStreamTransport) deliver(req *StreamRequest, deadline <-chan time.Time) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if !t.running {
+		return ErrUnreachable
+	}
+
+	select {
+	case t.reqCh <- req:
+		return nil
+	case <-t.done:
+		return ErrUnreachable
+	case <-deadline:
+		return ErrRequestTimeout
+	}
+}
@@ -2,10 +2,20 @@ package gossip
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
 )
 
+var (
+	ErrTransportStopped = errors.New("transport is stopped")
+	ErrTransportStarted = errors.New("transport is already started")
+	ErrPacketTooLarge   = errors.New("packet exceeds max packet size")
+	ErrFrameTooLarge    = errors.New("frame exceeds max frame size")
+	ErrNoResponse       = errors.New("request closed without a response")
+)
+
 // Transport handles the network layer for gossip comms
 type Transport interface {
 	// TStart begins listening for incoming messages
@@ -25,3 +35,50 @@ type Transport interface {
 	// LocalAddr returns the address this transport is listening on
 	LocalAddr() string
 }
+
+// StreamTransport handles reliable request/response exchanges that are too
+// large for a single datagram, such as a push-pull Sync of the full membership
+type StreamTransport interface {
+	// Start begins accepting incoming connections
+	Start(ctx context.Context) error
+
+	// Stop closes all connections and shuts down the transport
+	Stop() error
+
+	// Request sends msg to addr and waits for the peer's response
+	// Returns an error if the transport is not running or the exchange fails
+	Request(addr string, msg []byte) ([]byte, error)
+
+	// Requests returns a channel of received requests, each of which must
+	// be answered with Respond. The channel is closed when the transport stops
+	Requests() <-chan *StreamRequest
+
+	// LocalAddr returns the address this transport is listening on
+	LocalAddr() string
+}
+
+// StreamRequest is a request received over a StreamTransport
+type StreamRequest struct {
+	types.NetworkMessage
+	respCh chan []byte
+}
+
+func newStreamRequest(payload []byte, from string) *StreamRequest {
+	return &StreamRequest{
+		NetworkMessage: types.NetworkMessage{
+			Payload: payload,
+			From:    from,
+			Time:    time.Now(),
+		},
+		respCh: make(chan []byte, 1),
+	}
+}
+
+// Respond sends msg back to the requester. Only the first call has any
+// effect, and a nil msg ends the exchange without a reply
+func (r *StreamRequest) Respond(msg []byte) {
+	select {
+	case r.respCh <- msg:
+	default:
+	}
+}
@@ -191,3 +191,48 @@ func TestTestNetwork_MessageToUnknownAddrIsDropped(t *testing.T) {
 		t.Error("send to unknown addr returned error: %V", err)
 	}
 }
+
+func TestTestNetwork_StreamRequestResponse(t *testing.T) {
+	network := NewTestNetwork()
+	s1 := network.NewStreamTransport("node1")
+	s2 := network.NewStreamTransport("node2")
+
+	ctx := context.Background()
+	if err := s1.Start(ctx); err != nil {
+		t.Errorf("failed to start s1")
+	}
+	if err := s2.Start(ctx); err != nil {
+		t.Errorf("failed to start s2")
+	}
+	serveEcho(s2)
+
+	resp, err := s1.Request("node2", []byte("hello"))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	if string(resp) != "echo:hello" {
+		t.Errorf("response = %q, want %q", resp, "echo:hello")
+	}
+}
+
+func TestTestNetwork_StreamPartitionIsUnreachable(t *testing.T) {
+	network := NewTestNetwork()
+	s1 := network.NewStreamTransport("node1")
+	s2 := network.NewStreamTransport("node2")
+
+	ctx := context.Background()
+	if err := s1.Start(ctx); err != nil {
+		t.Errorf("failed to start s1")
+	}
+	if err := s2.Start(ctx); err != nil {
+		t.Errorf("failed to start s2")
+	}
+	serveEcho(s2)
+
+	network.Partition("node1", "node2")
+
+	if _, err := s1.Request("node2", []byte("hello")); err != ErrUnreachable {
+		t.Errorf("err = %v, want ErrUnreachable", err)
+	}
+}
@@ -0,0 +1,167 @@
+package gossip
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/michael-martinez-dev/adaptive-hive/internal/config"
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+// udpMessageBuffer is how many received datagrams are held before new ones are dropped
+const udpMessageBuffer = 100
+
+type udpTransport struct {
+	bindAddr      string
+	maxPacketSize int
+
+	mu      sync.RWMutex
+	conn    *net.UDPConn
+	msgCh   chan *types.NetworkMessage
+	running bool
+	stopped bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewUDPTransport creates a Transport that sends and receives UDP datagrams
+// on BindAddr:BindPort. Datagrams larger than MaxPacketSize are rejected on
+// send and dropped on receive
+func NewUDPTransport(node config.NodeConfig, gossip config.GossipConfig) Transport {
+	return &udpTransport{
+		bindAddr:      net.JoinHostPort(node.BindAddr, node.BindPort),
+		maxPacketSize: gossip.MaxPacketSize,
+		msgCh:         make(chan *types.NetworkMessage, udpMessageBuffer),
+		done:          make(chan struct{}),
+	}
+}
+
+// Start binds the socket and begins reading datagrams. The transport stops
+// itself when ctx is cancelled. A stopped transport cannot be restarted
+func (t *udpTransport) Start(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped {
+		return ErrTransportStopped
+	}
+	if t.running {
+		return ErrTransportStarted
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", t.bindAddr)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+
+	t.conn = conn
+	t.running = true
+
+	t.wg.Add(1)
+	go t.readLoop()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = t.Stop()
+		case <-t.done:
+		}
+	}()
+
+	return nil
+}
+
+// Stop closes the socket and waits for the read loop to exit, which closes
+// the Messages channel
+func (t *udpTransport) Stop() error {
+	t.mu.Lock()
+	if !t.running {
+		t.mu.Unlock()
+		return nil
+	}
+	t.running = false
+	t.stopped = true
+	close(t.done)
+	err := t.conn.Close()
+	t.mu.Unlock()
+
+	t.wg.Wait()
+	return err
+}
+
+func (t *udpTransport) SendTo(addr string, msg []byte) error {
+	if len(msg) > t.maxPacketSize {
+		return ErrPacketTooLarge
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if !t.running {
+		return ErrTransportStopped
+	}
+
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.conn.WriteToUDP(msg, udpAddr)
+	return err
+}
+
+func (t *udpTransport) Messages() <-chan *types.NetworkMessage {
+	return t.msgCh
+}
+
+func (t *udpTransport) LocalAddr() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.conn == nil {
+		return t.bindAddr
+	}
+	return t.conn.LocalAddr().String()
+}
+
+func (t *udpTransport) readLoop() {
+	defer t.wg.Done()
+	defer close(t.msgCh)
+
+	// One extra byte lets us tell a full-size datagram from a truncated one
+	buf := make([]byte, t.maxPacketSize+1)
+	for {
+		n, from, err := t.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		if n == 0 || n > t.maxPacketSize {
+			continue // empty or oversized datagram - DROP
+		}
+
+		payload := make([]byte, n)
+		copy(payload, buf[:n])
+
+		select {
+		case t.msgCh <- &types.NetworkMessage{
+			Payload: payload,
+			From:    from.String(),
+			Time:    time.Now(),
+		}:
+		default:
+			// channel full - DROP
+		}
+	}
+}
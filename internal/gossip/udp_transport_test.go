@@ -0,0 +1,140 @@
+package gossip
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/michael-martinez-dev/adaptive-hive/internal/config"
+)
+
+// --- Helpers --- //
+
+func newLoopbackUDPTransport(t *testing.T, maxPacketSize int) Transport {
+	t.Helper()
+	node := config.NodeConfig{BindAddr: "127.0.0.1", BindPort: "0"}
+	gossip := config.GossipConfig{MaxPacketSize: maxPacketSize}
+
+	transport := NewUDPTransport(node, gossip)
+	if err := transport.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start udp transport: %v", err)
+	}
+	t.Cleanup(func() { _ = transport.Stop() })
+	return transport
+}
+
+func waitForClose(t *testing.T, transport Transport, timeout time.Duration) {
+	t.Helper()
+	deadline := time.After(timeout)
+	for {
+		select {
+		case _, ok := <-transport.Messages():
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("timed out waiting for messages channel to close")
+		}
+	}
+}
+
+func TestUDPTransport_SendReceive(t *testing.T) {
+	t1 := newLoopbackUDPTransport(t, 1400)
+	t2 := newLoopbackUDPTransport(t, 1400)
+
+	if err := t1.SendTo(t2.LocalAddr(), []byte("hello")); err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+
+	msg := waitForMessage(t, t2.Messages(), time.Second)
+
+	if string(msg.Payload) != "hello" {
+		t.Errorf("payload = %q, want %q", msg.Payload, "hello")
+	}
+
+	if msg.From != t1.LocalAddr() {
+		t.Errorf("from = %q, want %q", msg.From, t1.LocalAddr())
+	}
+
+	if msg.Time.IsZero() {
+		t.Error("receive time was not set")
+	}
+}
+
+func TestUDPTransport_SendOversizedFails(t *testing.T) {
+	t1 := newLoopbackUDPTransport(t, 16)
+	t2 := newLoopbackUDPTransport(t, 16)
+
+	err := t1.SendTo(t2.LocalAddr(), []byte(strings.Repeat("x", 17)))
+	if err != ErrPacketTooLarge {
+		t.Errorf("err = %v, want ErrPacketTooLarge", err)
+	}
+}
+
+func TestUDPTransport_ReceiveOversizedDropped(t *testing.T) {
+	t1 := newLoopbackUDPTransport(t, 16)
+
+	conn, err := net.Dial("udp", t1.LocalAddr())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(strings.Repeat("x", 17))); err != nil {
+		t.Fatalf("failed to write oversized datagram: %v", err)
+	}
+	assertNoMessage(t, t1.Messages(), 100*time.Millisecond)
+
+	if _, err := conn.Write([]byte(strings.Repeat("x", 16))); err != nil {
+		t.Fatalf("failed to write datagram: %v", err)
+	}
+	msg := waitForMessage(t, t1.Messages(), time.Second)
+	if len(msg.Payload) != 16 {
+		t.Errorf("payload length = %d, want 16", len(msg.Payload))
+	}
+}
+
+func TestUDPTransport_StopClosesMessages(t *testing.T) {
+	t1 := newLoopbackUDPTransport(t, 1400)
+
+	if err := t1.Stop(); err != nil {
+		t.Fatalf("failed to stop: %v", err)
+	}
+	waitForClose(t, t1, time.Second)
+
+	err := t1.SendTo("127.0.0.1:1", []byte("hello"))
+	if err != ErrTransportStopped {
+		t.Errorf("err = %v, want ErrTransportStopped", err)
+	}
+}
+
+func TestUDPTransport_ContextCancelClosesMessages(t *testing.T) {
+	node := config.NodeConfig{BindAddr: "127.0.0.1", BindPort: "0"}
+	transport := NewUDPTransport(node, config.GossipConfig{MaxPacketSize: 1400})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := transport.Start(ctx); err != nil {
+		t.Fatalf("failed to start udp transport: %v", err)
+	}
+
+	cancel()
+	waitForClose(t, transport, time.Second)
+}
+
+func TestUDPTransport_RestartAfterStopFails(t *testing.T) {
+	t1 := newLoopbackUDPTransport(t, 1400)
+
+	if err := t1.Start(context.Background()); err != ErrTransportStarted {
+		t.Errorf("err = %v, want ErrTransportStarted", err)
+	}
+
+	if err := t1.Stop(); err != nil {
+		t.Fatalf("failed to stop: %v", err)
+	}
+
+	if err := t1.Start(context.Background()); err != ErrTransportStopped {
+		t.Errorf("err = %v, want ErrTransportStopped", err)
+	}
+}
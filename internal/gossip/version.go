@@ -0,0 +1,172 @@
+package gossip
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+var ErrUnsupportedVersion = errors.New("unsupported protocol version")
+
+// Gossip protocol versions, each tied to a wire format. Nodes advertise the
+// versions they speak in NodeMetadata.Protocol and senders pick the highest
+// one both sides share, stamping it in MessageHeader.Version
+const (
+	ProtocolV1 uint8 = 1 // gob, see NewCodec
+	ProtocolV2 uint8 = 2 // binary, see NewBinaryCodec
+)
+
+// UnsupportedVersionError is returned for a message or codec range using a
+// protocol version this node does not speak. It matches
+// ErrUnsupportedVersion with errors.Is
+type UnsupportedVersionError struct {
+	Version   uint8
+	Supported types.ProtocolRange
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("unsupported protocol version %d, this node speaks %d to %d",
+		e.Version, e.Supported.Min, e.Supported.Max)
+}
+
+func (e *UnsupportedVersionError) Is(target error) bool {
+	return target == ErrUnsupportedVersion
+}
+
+// versionedCodec is implemented by codecs that know which protocol versions
+// they speak
+type versionedCodec interface {
+	versions() types.ProtocolRange
+}
+
+func (c *codec) versions() types.ProtocolRange {
+	return types.ProtocolRange{Min: ProtocolV1, Max: ProtocolV1}
+}
+
+func (binaryCodec) versions() types.ProtocolRange {
+	return types.ProtocolRange{Min: ProtocolV2, Max: ProtocolV2}
+}
+
+// codecVersions returns the versions c speaks. Codecs that do not say are
+// assumed to speak version 1 only
+func codecVersions(c Codec) types.ProtocolRange {
+	if v, ok := c.(versionedCodec); ok {
+		return v.versions()
+	}
+	return types.ProtocolRange{Min: ProtocolV1, Max: ProtocolV1}
+}
+
+// negotiateVersion returns the highest version both local and peer speak
+func negotiateVersion(local, peer types.ProtocolRange) (uint8, bool) {
+	if peer == (types.ProtocolRange{}) {
+		peer = types.ProtocolRange{Min: ProtocolV1, Max: ProtocolV1}
+	}
+
+	lo, hi := max(local.Min, peer.Min), min(local.Max, peer.Max)
+	if hi < lo {
+		return 0, false
+	}
+	return hi, true
+}
+
+// setVersion stamps version in msg's header
+func setVersion(msg any, version uint8) {
+	if m, ok := msg.(headed); ok {
+		m.messageHeader().Version = version
+	}
+}
+
+// versionFor returns the protocol version to use with the peer at addr.
+// Peers we know nothing about get our lowest version
+func (g *gossiper) versionFor(addr string) uint8 {
+	node, _ := g.members.GetNodeByAddress(addr)
+	return g.versionWith(node)
+}
+
+// versionWith returns the protocol version to use with node
+func (g *gossiper) versionWith(node types.Node) uint8 {
+	if version, ok := negotiateVersion(g.protocol, node.Metadata.Protocol); ok {
+		return version
+	}
+	return g.protocol.Min // the peer will reject it, with a clear error
+}
+
+type negotiatingCodec struct {
+	supported types.ProtocolRange
+	formats   map[uint8]Codec
+}
+
+// NewVersionedCodec returns a Codec speaking protocol versions minVersion
+// to maxVersion. Encode uses the wire format of the version set in the
+// message header, or minVersion if none is set. Decode recognises the wire
+// format on its own and rejects versions outside the range with an
+// UnsupportedVersionError. opts apply to the codec of every version
+func NewVersionedCodec(minVersion, maxVersion uint8, opts ...CodecOption) (Codec, error) {
+	supported := types.ProtocolRange{Min: minVersion, Max: maxVersion}
+	if minVersion < ProtocolV1 || maxVersion > ProtocolV2 || minVersion > maxVersion {
+		return nil, fmt.Errorf("%w: cannot speak %d to %d", ErrUnsupportedVersion, minVersion, maxVersion)
+	}
+
+	return &negotiatingCodec{
+		supported: supported,
+		formats: map[uint8]Codec{
+			ProtocolV1: NewCodec(opts...),
+			ProtocolV2: NewBinaryCodec(opts...),
+		},
+	}, nil
+}
+
+func (c *negotiatingCodec) versions() types.ProtocolRange {
+	return c.supported
+}
+
+func (c *negotiatingCodec) Encode(msg any) ([]byte, error) {
+	return c.encode(msg, true)
+}
+
+func (c *negotiatingCodec) encode(msg any, compressible bool) ([]byte, error) {
+	version := c.supported.Min
+	if m, ok := msg.(headed); ok && m.messageHeader().Version != 0 {
+		version = m.messageHeader().Version
+	}
+	if err := c.check(version); err != nil {
+		return nil, err
+	}
+	return c.formats[version].(compressingCodec).encode(msg, compressible)
+}
+
+func (c *negotiatingCodec) Decode(data []byte) (any, error) {
+	// A gob stream never starts with binaryMagic: its first byte is either
+	// a length below 0x80 or a byte count of 0xf8 and above
+	format := ProtocolV1
+	if len(data) > 0 && data[0] == binaryMagic {
+		format = ProtocolV2
+	}
+	if err := c.check(format); err != nil {
+		return nil, err
+	}
+
+	msg, err := c.formats[format].Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if m, ok := msg.(headed); ok {
+		h := m.messageHeader()
+		if h.Version == 0 {
+			h.Version = format // from a peer that predates versioning
+		}
+		if err := c.check(h.Version); err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
+
+func (c *negotiatingCodec) check(version uint8) error {
+	if version < c.supported.Min || version > c.supported.Max {
+		return &UnsupportedVersionError{Version: version, Supported: c.supported}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package gossip
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/michael-martinez-dev/adaptive-hive/pkg/types"
+)
+
+func mustVersionedCodec(t *testing.T, minVersion, maxVersion uint8) Codec {
+	t.Helper()
+
+	codec, err := NewVersionedCodec(minVersion, maxVersion)
+	if err != nil {
+		t.Fatalf("NewVersionedCodec(%d, %d) failed: %v", minVersion, maxVersion, err)
+	}
+	return codec
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		local, peer types.ProtocolRange
+		want        uint8
+		wantOK      bool
+	}{
+		{"same range", types.ProtocolRange{Min: 1, Max: 2}, types.ProtocolRange{Min: 1, Max: 2}, 2, true},
+		{"older peer", types.ProtocolRange{Min: 1, Max: 2}, types.ProtocolRange{Min: 1, Max: 1}, 1, true},
+		{"newer peer", types.ProtocolRange{Min: 1, Max: 1}, types.ProtocolRange{Min: 1, Max: 2}, 1, true},
+		{"unversioned peer", types.ProtocolRange{Min: 1, Max: 2}, types.ProtocolRange{}, 1, true},
+		{"no overlap", types.ProtocolRange{Min: 2, Max: 2}, types.ProtocolRange{Min: 1, Max: 1}, 0, false},
+		{"unversioned peer, no overlap", types.ProtocolRange{Min: 2, Max: 2}, types.ProtocolRange{}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := negotiateVersion(tt.local, tt.peer)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("negotiateVersion = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestVersionedCodec_InvalidRange(t *testing.T) {
+	for _, r := range []types.ProtocolRange{{Min: 0, Max: 1}, {Min: 2, Max: 1}, {Min: 1, Max: 3}} {
+		if _, err := NewVersionedCodec(r.Min, r.Max); !errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("NewVersionedCodec(%d, %d): err = %v, want ErrUnsupportedVersion", r.Min, r.Max, err)
+		}
+	}
+}
+
+func TestVersionedCodec_CompatibilityMatrix(t *testing.T) {
+	// Every pairing of old and new codecs: messages get through whenever
+	// the two share a version, and versioned receivers reject them clearly
+	// otherwise
+	codecs := map[string]Codec{
+		"gob":    NewCodec(),
+		"binary": NewBinaryCodec(),
+		"v1":     mustVersionedCodec(t, 1, 1),
+		"v1-v2":  mustVersionedCodec(t, 1, 2),
+		"v2":     mustVersionedCodec(t, 2, 2),
+	}
+
+	for senderName, sender := range codecs {
+		for receiverName, receiver := range codecs {
+			t.Run(senderName+" to "+receiverName, func(t *testing.T) {
+				version, compatible := negotiateVersion(codecVersions(sender), codecVersions(receiver))
+				if !compatible {
+					version = codecVersions(sender).Min
+				}
+
+				for _, tt := range allMessages() {
+					setVersion(tt.msg, version)
+					data, err := sender.Encode(tt.msg)
+					if err != nil {
+						t.Fatalf("%s: Encode failed: %v", tt.name, err)
+					}
+
+					decoded, err := receiver.Decode(data)
+					switch {
+					case compatible && err != nil:
+						t.Fatalf("%s: Decode failed: %v", tt.name, err)
+					case compatible && !reflect.DeepEqual(decoded, tt.msg):
+						t.Fatalf("%s: decoded = %+v, want %+v", tt.name, decoded, tt.msg)
+					case !compatible && err == nil:
+						t.Fatalf("%s: Decode succeeded between incompatible codecs", tt.name)
+					}
+
+					var unsupported *UnsupportedVersionError
+					_, versioned := receiver.(*negotiatingCodec)
+					if !compatible && versioned && (!errors.As(err, &unsupported) || unsupported.Version != version) {
+						t.Fatalf("%s: err = %v, want UnsupportedVersionError for version %d", tt.name, err, version)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestVersionedCodec_UnversionedPeer(t *testing.T) {
+	// Peers that predate versioning send gob without setting Version
+	codec := mustVersionedCodec(t, 1, 2)
+
+	data := mustEncode(t, NewCodec(), &Nack{MessageHeader: MessageHeader{Type: MessageTypeNack, SourceID: "old"}})
+	decoded, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if nack := decoded.(*Nack); nack.Version != ProtocolV1 {
+		t.Errorf("Version = %d, want %d", nack.Version, ProtocolV1)
+	}
+}
+
+func TestVersionedCodec_RejectsNewerVersion(t *testing.T) {
+	// A future version reusing a known wire format is still rejected
+	codec := mustVersionedCodec(t, 1, 2)
+
+	data := mustEncode(t, NewBinaryCodec(), &Nack{MessageHeader: MessageHeader{Version: 3, Type: MessageTypeNack}})
+	if _, err := codec.Decode(data); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("err = %v, want ErrUnsupportedVersion", err)
+	}
+	if _, err := codec.Encode(&Nack{MessageHeader: MessageHeader{Version: 3, Type: MessageTypeNack}}); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Encode err = %v, want ErrUnsupportedVersion", err)
+	}
+}
+
+func TestGossiper_MixedVersionsConverge(t *testing.T) {
+	network := NewTestNetwork()
+	old := newTestGossiperWithConfig(t, testGossiperConfig("node1"), network.NewTransport("node1"), NewCodec())
+	g2 := newTestGossiperWithConfig(t, testGossiperConfig("node2"), network.NewTransport("node2"), mustVersionedCodec(t, 1, 2))
+	g3 := newTestGossiperWithConfig(t, testGossiperConfig("node3"), network.NewTransport("node3"), mustVersionedCodec(t, 1, 2))
+
+	for _, g := range []*gossiper{g2, g3} {
+		if err := g.Join("node1"); err != nil {
+			t.Fatalf("failed to join: %v", err)
+		}
+	}
+
+	all := []types.NodeID{"node1", "node2", "node3"}
+	for _, g := range []Gossiper{old, g2, g3} {
+		waitFor(t, 3*time.Second, func() bool { return knowsAlive(g, all...) }, "membership to converge")
+	}
+
+	if v := g2.versionFor("node3"); v != ProtocolV2 {
+		t.Errorf("node2 speaks version %d to node3, want %d", v, ProtocolV2)
+	}
+	if v := g2.versionFor("node1"); v != ProtocolV1 {
+		t.Errorf("node2 speaks version %d to node1, want %d", v, ProtocolV1)
+	}
+}
+
+func TestGossiper_IncompatibleSeedFails(t *testing.T) {
+	network := NewTestNetwork()
+	newTestGossiperWithConfig(t, testGossiperConfig("node1"), network.NewTransport("node1"), mustVersionedCodec(t, 1, 1))
+	g2 := newTestGossiperWithConfig(t, testGossiperConfig("node2"), network.NewTransport("node2"), mustVersionedCodec(t, 2, 2))
+
+	if err := g2.Join("node1"); !errors.Is(err, ErrNoSeedsResponded) {
+		t.Errorf("Join err = %v, want ErrNoSeedsResponded", err)
+	}
+}
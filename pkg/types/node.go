@@ -37,8 +37,13 @@ type Node struct {
 	Address     string // host:port for gossip comms
 	State       NodeState
 	Incarnation uint64 // Lanport-like counter for state consistency
+	Cluster     string // cluster the node belongs to, empty if unknown
 	Metadata    NodeMetadata
 	LastUpdated time.Time
+
+	// MetadataIncarnation is the incarnation Metadata was published at,
+	// zero if it was never learned
+	MetadataIncarnation uint64
 }
 
 // NodeMetadata contains additional information about a node's capabilities
@@ -46,6 +51,24 @@ type NodeMetadata struct {
 	Resources Resources
 	Labels    map[string]string // for scheduling constraints
 	Priority  int
+	Protocol  ProtocolRange // gossip protocol versions the node speaks
+
+	// Compression is set by nodes that decode compressed payloads
+	Compression bool
+
+	// Compound is set by nodes that unpack compound messages
+	Compound bool
+
+	// Gossip is set by nodes that decode Gossip messages
+	Gossip bool
+}
+
+// ProtocolRange is an inclusive range of gossip protocol versions. The zero
+// value is advertised by nodes that predate versioning, which only speak
+// version 1
+type ProtocolRange struct {
+	Min uint8
+	Max uint8
 }
 
 // Resources describes the available resources on a node